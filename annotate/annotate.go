@@ -0,0 +1,915 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+// Package annotate instruments Go source files with calls to a log package that
+// records the entry, exit and panics of functions, and removes the instrumentation
+// again. It is the engine of the go-annotate command.
+package annotate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const (
+	importName   = "__log"
+	resultPrefix = "res"
+
+	argPrefix         = "__arg"
+	blankPrefix       = "__blank"
+	blankResultPrefix = "__blankRes"
+	namedResultPrefix = "__res"
+	recvName          = "__recv"
+	blankRecvName     = "__blankRecv"
+	separator         = "_"
+
+	// endMarker is the comment that ends instrumentation generated from custom
+	// templates, which has no fixed shape, and, with the argument oneline, the
+	// instrumentation of a body written on one line, so that stripping restores it.
+	endMarker = directivePrefix + "end"
+	oneline   = "oneline"
+
+	enterTmpl = `
+__traceID := __log.ID()
+__log.LogEnter(__traceID, {{.Event}}, []any{{"{"}}{{.ArgValues}}{{"}"}}){{if .Timing}}
+__traceStart := __log.Now(){{end}}`
+
+	initTmpl = `
+func init() {
+	__log.SetFormatLength(%d)
+}
+`
+
+	leaveTmpl = `
+defer func() {
+	if __panic := recover(); __panic != nil {
+		__log.LogPanic(__traceID, {{.Event}}, []any{{"{"}}{{.ArgValues}}{{"}"}}, __panic, {{.Stack}})
+		panic(__panic)
+	}
+	{{- if .Timing}}
+	__log.LogLeaveTimed(__traceID, {{.Event}}, []any{{"{"}}{{.ArgValues}}{{"}"}}, []any{{"{"}}{{.ResultValues}}{{"}"}}, __traceStart)
+	{{- else}}
+	__log.LogLeave(__traceID, {{.Event}}, []any{{"{"}}{{.ArgValues}}{{"}"}}, []any{{"{"}}{{.ResultValues}}{{"}"}})
+	{{- end}}
+}()`
+)
+
+// Options configures the instrumentation added by an annotator.
+type Options struct {
+	// ImportPath is the import path of the log package called by the instrumentation.
+	ImportPath   string
+	ShowReturn   bool
+	ExportedOnly bool
+	Prefix       string
+	ShowPackage  bool
+	// FormatLength limits the formatted length of each logged value, 0 disables it.
+	FormatLength int
+	Timing       bool
+	Closures     bool
+	Defer        bool
+	Stack        bool
+	// Types records static types, which requires packages loaded with type information.
+	Types     bool
+	BuildTags string
+	// LineDirectives adds //line directives referring to the absolute paths of the files.
+	LineDirectives bool
+	// Strip removes the instrumentation instead of adding it.
+	Strip bool
+	// KeepGoing skips functions that cannot be instrumented instead of failing the file.
+	KeepGoing    bool
+	Include      []string
+	Exclude      []string
+	IncludeFiles []string
+	ExcludeFiles []string
+	// Jobs is the number of files processed concurrently. Zero or less means
+	// runtime.GOMAXPROCS(0).
+	Jobs int
+	// CacheDir, if set, is a directory in which processed files are kept, keyed by
+	// their content, the options and the annotator build, so that unchanged files
+	// are not processed again.
+	CacheDir string
+	// Template, if set, defines the templates "enter" and "leave" that generate the
+	// statements added at the start of each function instead of the built-in calls,
+	// from TemplateData. The leave statements must defer the logging of the results.
+	// The instrumentation is followed by an end marker comment for stripping.
+	Template string
+
+	// FileOptions, if set, returns the options for each processed file, for example
+	// from configuration files next to it. They replace these options for the file.
+	// It may be called concurrently.
+	FileOptions func(file string) (*Options, error)
+}
+
+// Annotator adds instrumentation to Go source files and collects the manifest of the
+// instrumented functions.
+type Annotator struct {
+	config        *Options
+	filter        *filter
+	fset          *token.FileSet
+	enterTemplate *template.Template
+	leaveTemplate *template.Template
+	functions     []Function
+	types         map[string]*typeInfo
+	packagePaths  map[string]string
+	summary       Summary
+}
+
+// functionInfo holds extracted information about a function. The types and conversions
+// are only known in type-checked mode. HasNamedReturn refers to the signature the
+// information was extracted from, NamedInSource to the results as written.
+type functionInfo struct {
+	Name           string
+	ReceiverNames  []string
+	ArgNames       []string
+	RetNames       []string
+	HasNamedReturn bool
+	NamedInSource  bool
+	ReceiverTypes  []string
+	ArgTypes       []string
+	RetTypes       []string
+	Conversions    map[string]string
+}
+
+// New creates an annotator with the given options.
+func New(config *Options) (*Annotator, error) {
+	enterTemplate, leaveTemplate, err := parseTemplates(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := newFilter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Annotator{
+		config:        config,
+		filter:        filter,
+		fset:          token.NewFileSet(),
+		enterTemplate: enterTemplate,
+		leaveTemplate: leaveTemplate,
+		types:         make(map[string]*typeInfo),
+		packagePaths:  make(map[string]string),
+	}, nil
+}
+
+// debugCall generates enter and leave statement strings for function instrumentation.
+// The event name is a Go expression as returned by eventName.
+func (a *Annotator) debugCall(fn Function, event string, pos token.Pos, args []string, results []string) (string, string, error) {
+	data := TemplateData{
+		Function:     fn,
+		Event:        event,
+		ArgValues:    strings.Join(args, ", "),
+		ResultValues: strings.Join(results, ", "),
+		Timing:       a.config.Timing,
+		ShowReturn:   a.config.ShowReturn,
+	}
+	if pos.IsValid() {
+		data.Position = a.fset.Position(pos).String()
+	}
+
+	return executeTemplates(a.enterTemplate, a.leaveTemplate, data)
+}
+
+// AnnotateSource parses Go source code and annotates functions with instrumentation.
+// Functions that are already instrumented are stripped and annotated again, so running
+// the annotator repeatedly is safe and picks up configuration changes. The source is
+// taken as a package of its own, which sets the format length.
+func (a *Annotator) AnnotateSource(filename string, orig []byte) ([]byte, error) {
+	r := &Result{Filename: filename, Source: orig}
+	if err := a.annotateSource(r); err != nil {
+		return r.Output, err
+	}
+
+	addInits([]*Result{r})
+	return r.Output, nil
+}
+
+// annotateSource annotates the source of a result. Unless no function was instrumented,
+// it also records the formatted original source that the instrumentation was added to
+// and which declarations and statements of the output were generated.
+func (a *Annotator) annotateSource(r *Result) error {
+	filename, orig := r.Filename, r.Source
+	if importsLog(orig) {
+		stripped, err := a.StripSource(filename, orig)
+		if err != nil {
+			return err
+		}
+		orig = stripped
+	}
+
+	orig, err := format.Source(orig)
+	if err != nil {
+		return err
+	}
+
+	f, err := parser.ParseFile(a.fset, filename, orig, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	dirs := fileDirectives(a.fset, f)
+
+	packageName := f.Name.Name
+	requiresImport := false
+	ti := a.typesFor(filename)
+	packagePath := a.packagePathFor(filename)
+
+	var closures map[*ast.FuncLit]closure
+	if a.config.Closures {
+		closures = closureNames(f)
+	}
+
+	// The instrumentation is inserted as text, so that the original code and its
+	// comments are left untouched.
+	var edits []edit
+	// A failing function stops the traversal unless the annotator keeps going.
+	var failure error
+	astutil.Apply(f, nil, func(c *astutil.Cursor) bool {
+		switch node := c.Node().(type) {
+		case *ast.FuncDecl:
+			if node.Body == nil {
+				return true
+			}
+
+			d := dirs[node]
+			if d.err != nil {
+				failure = a.funcError(node.Pos(), funcName(node), d.err)
+				return failure == nil
+			}
+			if d.skip || !a.selected(node, funcName(node)) {
+				a.summary.Skipped++
+				return true
+			}
+
+			funcEdits, fn, err := a.annotateFunction(orig, node, packageName, packagePath, d, ti)
+			if err != nil {
+				failure = a.funcError(node.Pos(), funcName(node), err)
+				return failure == nil
+			}
+			edits = append(edits, funcEdits...)
+			requiresImport = true
+			a.functions = append(a.functions, fn)
+			a.summary.Instrumented++
+		case *ast.FuncLit:
+			// The literals in a function with invalid directives fail with it.
+			cl, ok := closures[node]
+			if !ok || dirs[cl.decl].err != nil {
+				return true
+			}
+			if dirs[cl.decl].skip || !a.selected(cl.decl, cl.name()) {
+				a.summary.Skipped++
+				return true
+			}
+
+			d := dirs[cl.decl]
+			nameExpr, pattern := a.closureEventName(cl, packageName, d.name)
+			litEdits, fn, err := a.annotateFuncLit(orig, node, cl.nameAs(d.name), nameExpr, pattern, packageName, packagePath)
+			if err != nil {
+				failure = a.funcError(node.Pos(), "", err)
+				return failure == nil
+			}
+			edits = append(edits, litEdits...)
+			requiresImport = true
+			a.functions = append(a.functions, fn)
+			a.summary.Instrumented++
+		}
+		return true
+	})
+	if failure != nil {
+		return failure
+	}
+
+	annotated, err := parser.ParseFile(a.fset, filename, applyEdits(orig, edits), parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse annotated source: %w", err)
+	}
+
+	if requiresImport {
+		astutil.AddNamedImport(a.fset, annotated, importName, a.config.ImportPath)
+	}
+	generated := a.generatedNodes(annotated, edits)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, a.fset, annotated); err != nil {
+		return fmt.Errorf("format.Node: %w", err)
+	}
+
+	if a.config.LineDirectives && requiresImport {
+		src, err := a.addLineDirectives(filename, f, buf.Bytes(), generated)
+		if err != nil {
+			return err
+		}
+		buf.Reset()
+		buf.Write(src)
+	}
+
+	// The init function that configures the go-annotate logger is added to one file of
+	// each package by addInits. Custom templates need not call the logger.
+	if requiresImport && a.config.Template == "" {
+		r.formatLength = a.config.FormatLength
+	}
+
+	r.Output = buf.Bytes()
+	if requiresImport {
+		r.base, r.generated = orig, generated
+	}
+	return nil
+}
+
+// importsLog reports whether a source file imports a log package under the name used
+// by the instrumentation, which marks it as annotated.
+func importsLog(src []byte) bool {
+	if !bytes.Contains(src, []byte(importName)) {
+		return false
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return true
+	}
+
+	for _, spec := range f.Imports {
+		if spec.Name != nil && spec.Name.Name == importName {
+			return true
+		}
+	}
+	return false
+}
+
+// selected reports whether a function with the given qualified name is instrumented.
+// Function literals are selected by their own name and by their enclosing declaration,
+// where a nil declaration stands for package-level code, which is unexported.
+func (a *Annotator) selected(decl *ast.FuncDecl, name string) bool {
+	if a.config.ExportedOnly && (decl == nil || !ast.IsExported(decl.Name.Name)) {
+		return false
+	}
+
+	return a.filter.function(name)
+}
+
+// extractFunctionInfo extracts metadata from a function declaration.
+func (a *Annotator) extractFunctionInfo(target *ast.FuncDecl) *functionInfo {
+	return newFunctionInfo(funcName(target), target.Recv, target.Type)
+}
+
+// newFunctionInfo extracts metadata from the receiver and signature of a function
+// declaration or function literal.
+func newFunctionInfo(name string, recv *ast.FieldList, typ *ast.FuncType) *functionInfo {
+	info := &functionInfo{
+		Name: name,
+	}
+
+	// If the function is a method, add the receiver to the argument list.
+	if recv != nil {
+		for _, param := range recv.List {
+			for _, name := range param.Names {
+				info.ReceiverNames = append(info.ReceiverNames, name.Name)
+			}
+		}
+	}
+
+	info.ArgNames = paramNames(typ.Params)
+	info.RetNames = resultNames(typ.Results)
+
+	info.HasNamedReturn = hasNamedResults(typ)
+	info.NamedInSource = info.HasNamedReturn
+
+	return info
+}
+
+// hasNamedResults reports whether the results of a function type are named.
+func hasNamedResults(typ *ast.FuncType) bool {
+	return typ.Results != nil && len(typ.Results.List) > 0 && len(typ.Results.List[0].Names) > 0
+}
+
+// annotateFunction returns the edits that add instrumentation logging to a function
+// declaration, following the directives in its doc comment, and its manifest entry.
+// With type information, the static types are recorded and used for logging.
+func (a *Annotator) annotateFunction(src []byte, target *ast.FuncDecl, packageName string, packagePath string, d directives, ti *typeInfo) ([]edit, Function, error) {
+	recv, typ := nameSignature(target.Recv, target.Type, a.config.Defer)
+	info := newFunctionInfo(funcName(target), recv, typ)
+	info.NamedInSource = hasNamedResults(target.Type)
+	if sig := ti.signature(target); sig != nil {
+		info.addTypes(sig, ti.pkg)
+	}
+	nameExpr, pattern := a.eventName(target, packageName, "")
+	if d.name != "" {
+		info.Name = d.name
+		nameExpr, pattern = a.staticEventName(d.name, packageName), ""
+	}
+
+	edits, fn, err := a.annotateBody(src, funcBody{
+		info:        info,
+		typ:         typ,
+		body:        target.Body,
+		event:       nameExpr,
+		pattern:     pattern,
+		pos:         target.Pos(),
+		dirs:        d,
+		packageName: packageName,
+		packagePath: packagePath,
+	})
+	if err != nil {
+		return nil, Function{}, err
+	}
+	edits = append(edits, a.nameEdits(target.Recv, recv)...)
+	edits = append(edits, a.nameEdits(target.Type.Params, typ.Params)...)
+	edits = append(edits, a.nameEdits(target.Type.Results, typ.Results)...)
+
+	return edits, fn, nil
+}
+
+// annotateFuncLit returns the edits that add instrumentation logging to a function literal.
+func (a *Annotator) annotateFuncLit(src []byte, target *ast.FuncLit, name string, nameExpr string, pattern string, packageName string, packagePath string) ([]edit, Function, error) {
+	_, typ := nameSignature(nil, target.Type, a.config.Defer)
+	info := newFunctionInfo(name, nil, typ)
+	info.NamedInSource = hasNamedResults(target.Type)
+
+	edits, fn, err := a.annotateBody(src, funcBody{
+		info:        info,
+		typ:         typ,
+		body:        target.Body,
+		event:       nameExpr,
+		pattern:     pattern,
+		pos:         target.Pos(),
+		packageName: packageName,
+		packagePath: packagePath,
+	})
+	if err != nil {
+		return nil, Function{}, err
+	}
+	edits = append(edits, a.nameEdits(target.Type.Params, typ.Params)...)
+	edits = append(edits, a.nameEdits(target.Type.Results, typ.Results)...)
+
+	return edits, fn, nil
+}
+
+// funcBody is a function declaration or function literal to be instrumented.
+type funcBody struct {
+	info *functionInfo
+	// typ is the signature with the synthetic names of nameSignature.
+	typ  *ast.FuncType
+	body *ast.BlockStmt
+	// event is the Go expression naming the events and pattern the pattern of the
+	// names, as returned by eventName.
+	event       string
+	pattern     string
+	pos         token.Pos
+	dirs        directives
+	packageName string
+	packagePath string
+}
+
+// annotateBody returns the edits that instrument the body of a function of a package
+// and its manifest entry. Redacted arguments and results are logged as a placeholder.
+func (a *Annotator) annotateBody(src []byte, b funcBody) ([]edit, Function, error) {
+	info := b.info
+	position := a.fset.PositionFor(b.pos, false)
+	fn := Function{
+		Name:         b.packageName + separator + info.Name,
+		Package:      b.packageName,
+		PackagePath:  b.packagePath,
+		File:         position.Filename,
+		Line:         position.Line,
+		Params:       newVars(info.ArgNames, info.ArgTypes),
+		Results:      newVars(info.RetNames, info.RetTypes),
+		NamedResults: info.NamedInSource,
+		Stack:        a.config.Stack,
+	}
+	if len(info.ReceiverNames) > 0 {
+		fn.Receiver = newVars(info.ReceiverNames, info.ReceiverTypes)
+	}
+	if info.Conversions != nil {
+		fn.Signature = info.signatureString()
+	}
+	fn.EventPattern = b.pattern
+
+	args := append(append([]string(nil), info.ReceiverNames...), info.ArgNames...)
+	enterStr, leaveStr, err := a.debugCall(fn, b.event, b.pos, b.dirs.values(args, info.Conversions), b.dirs.values(info.RetNames, info.Conversions))
+	if err != nil {
+		return nil, Function{}, err
+	}
+
+	// Custom instrumentation has no fixed shape, so it ends with a marker for stripping.
+	var marker string
+	if a.config.Template != "" {
+		marker = endMarker
+	}
+
+	// Without results, or with named results in defer mode, the deferred LogLeave sees
+	// the final results and the body stays in place. Otherwise the body is run as a
+	// closure whose results are assigned to the logged variables.
+	if len(info.RetNames) == 0 || a.config.Defer {
+		sameLine := a.fset.Position(b.body.Lbrace).Line == a.fset.Position(b.body.Rbrace).Line
+		if sameLine && len(b.body.List) > 0 {
+			marker = endMarker + " " + oneline
+		}
+
+		// The marker follows the last statement of the instrumentation on its line, and
+		// a body on the line of the opening brace starts on the next one.
+		end := ";"
+		switch {
+		case marker == "":
+		case sameLine:
+			end = " " + marker + "\n"
+		default:
+			end = " " + marker
+		}
+		return []edit{a.insertion(b.body.Lbrace+1, enterStr+leaveStr+end)}, fn, nil
+	}
+
+	var prologue strings.Builder
+	prologue.WriteString(enterStr)
+	if !info.HasNamedReturn {
+		prologue.WriteString(a.resultDecl(src, info, b.typ.Results))
+	}
+	prologue.WriteString(leaveStr)
+	if marker != "" {
+		prologue.WriteString(" " + marker)
+	}
+	fmt.Fprintf(&prologue, "\n%s = func() %s {", strings.Join(info.RetNames, ", "), a.resultsText(src, b.typ.Results))
+
+	epilogue := "}()\nreturn"
+	if !info.HasNamedReturn {
+		epilogue += " " + strings.Join(info.RetNames, ", ")
+	}
+
+	return []edit{
+		a.insertion(b.body.Lbrace+1, prologue.String()),
+		a.insertion(b.body.Rbrace, epilogue+"\n"),
+	}, fn, nil
+}
+
+// resultDecl returns the declaration of the unnamed function results. They are declared
+// ahead of the deferred LogLeave, which then also runs if the original body panics.
+func (a *Annotator) resultDecl(src []byte, info *functionInfo, results *ast.FieldList) string {
+	var specs []string
+	for i, field := range results.List {
+		specs = append(specs, info.RetNames[i]+" "+a.text(src, field.Type))
+	}
+
+	if len(specs) == 1 {
+		return "\nvar " + specs[0]
+	}
+	return "\nvar (\n" + strings.Join(specs, "\n") + "\n)"
+}
+
+// resultsText returns the result list of the closure that runs the original body.
+func (a *Annotator) resultsText(src []byte, results *ast.FieldList) string {
+	if len(results.List) == 1 && len(results.List[0].Names) == 0 {
+		return a.text(src, results.List[0].Type)
+	}
+
+	var fields []string
+	for _, field := range results.List {
+		var names []string
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+
+		text := a.text(src, field.Type)
+		if len(names) > 0 {
+			text = strings.Join(names, ", ") + " " + text
+		}
+		fields = append(fields, text)
+	}
+
+	return "(" + strings.Join(fields, ", ") + ")"
+}
+
+// nameEdits returns the edits that give the fields of a receiver, parameter or result
+// list the names chosen by nameSignature. A single result that is named needs parentheses.
+func (a *Annotator) nameEdits(list *ast.FieldList, named *ast.FieldList) []edit {
+	if list == nil {
+		return nil
+	}
+
+	var edits []edit
+	for i, field := range list.List {
+		names := named.List[i].Names
+		if len(field.Names) == 0 {
+			if len(names) == 0 {
+				continue
+			}
+
+			if !list.Opening.IsValid() {
+				edits = append(edits,
+					a.insertion(field.Type.Pos(), "("+names[0].Name+" "),
+					a.insertion(field.Type.End(), ")"))
+				continue
+			}
+			edits = append(edits, a.insertion(field.Type.Pos(), names[0].Name+" "))
+			continue
+		}
+
+		for j, name := range field.Names {
+			if name.Name != names[j].Name {
+				e := a.deletion(name.Pos(), name.End())
+				e.text = names[j].Name
+				edits = append(edits, e)
+			}
+		}
+	}
+
+	return edits
+}
+
+// insertion returns an edit that inserts text at a position.
+func (a *Annotator) insertion(pos token.Pos, text string) edit {
+	offset := a.fset.File(pos).Offset(pos)
+	return edit{start: offset, end: offset, text: text}
+}
+
+// text returns the source of a node.
+func (a *Annotator) text(src []byte, node ast.Node) string {
+	file := a.fset.File(node.Pos())
+	return string(src[file.Offset(node.Pos()):file.Offset(node.End())])
+}
+
+// nameSignature returns copies of a receiver and a function type in which unnamed and
+// blank parameters and receivers, and blank results, have synthetic names, so that
+// every value can be logged. Unnamed parameters are named __argN and blank ones __blankN
+// after their position, which lets stripNames restore the original signature. If
+// namedResults is set, unnamed results are named __resN as well.
+func nameSignature(recv *ast.FieldList, typ *ast.FuncType, namedResults bool) (*ast.FieldList, *ast.FuncType) {
+	var unnamedResult func(int) string
+	if namedResults {
+		unnamedResult = func(i int) string { return namedResultPrefix + strconv.Itoa(i) }
+	}
+
+	named := *typ
+	named.Params = nameFields(typ.Params, func(i int) string { return argPrefix + strconv.Itoa(i) }, func(i int) string { return blankPrefix + strconv.Itoa(i) })
+	named.Results = nameFields(typ.Results, unnamedResult, func(i int) string { return blankResultPrefix + strconv.Itoa(i) })
+
+	recv = nameFields(recv, func(int) string { return recvName }, func(int) string { return blankRecvName })
+	return recv, &named
+}
+
+// nameFields returns a copy of a field list in which unnamed and blank fields are given
+// the names returned by unnamed and blank for their position. Unnamed fields are kept
+// if unnamed is nil.
+func nameFields(list *ast.FieldList, unnamed func(int) string, blank func(int) string) *ast.FieldList {
+	if list == nil {
+		return nil
+	}
+
+	named := *list
+	named.List = make([]*ast.Field, len(list.List))
+
+	i := 0
+	for j, field := range list.List {
+		f := *field
+		named.List[j] = &f
+
+		if len(field.Names) == 0 {
+			if unnamed != nil {
+				f.Names = []*ast.Ident{ast.NewIdent(unnamed(i))}
+			}
+			i++
+			continue
+		}
+
+		f.Names = make([]*ast.Ident, len(field.Names))
+		for k, name := range field.Names {
+			f.Names[k] = name
+			if name.Name == "_" {
+				f.Names[k] = ast.NewIdent(blank(i))
+			}
+			i++
+		}
+	}
+
+	return &named
+}
+
+// paramNames converts function parameters to a list of names.
+func paramNames(params *ast.FieldList) []string {
+	var p []string
+	if (params == nil) || (params.List == nil) {
+		return p
+	}
+
+	for _, f := range params.List {
+		for _, n := range f.Names {
+			p = append(p, n.Name)
+		}
+	}
+	return p
+}
+
+// resultNames converts function parameters to a list of names.
+func resultNames(results *ast.FieldList) []string {
+	var p []string
+	if (results == nil) || (results.List == nil) {
+		return p
+	}
+
+	for _, f := range results.List {
+		if f.Names != nil {
+			for _, n := range f.Names {
+				p = append(p, n.Name)
+			}
+		} else {
+			p = append(p, fmt.Sprintf("%s%d", resultPrefix, len(p)+1))
+		}
+	}
+
+	return p
+}
+
+// funcName extracts the qualified name of a function, including receiver type for methods.
+func funcName(f *ast.FuncDecl) string {
+	if f.Recv != nil && len(f.Recv.List) > 0 {
+		name := recvTypeName(f.Recv.List[0].Type)
+		if name == "" {
+			return ""
+		}
+		return name + separator + f.Name.Name
+	}
+
+	return f.Name.Name
+}
+
+// recvTypeName returns the base type name of a receiver, e.g. Stack for *Stack[T].
+func recvTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return recvTypeName(t.X)
+	case *ast.ParenExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(t.X)
+	}
+
+	return ""
+}
+
+// recvTypeParams returns the type parameter names of a generic receiver, e.g. T for *Stack[T].
+func recvTypeParams(expr ast.Expr) []string {
+	var indices []ast.Expr
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return recvTypeParams(t.X)
+	case *ast.ParenExpr:
+		return recvTypeParams(t.X)
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+
+	var names []string
+	for _, index := range indices {
+		ident, ok := index.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		names = append(names, ident.Name)
+	}
+
+	return names
+}
+
+// eventName returns the Go expression naming the events of a function, followed by an
+// optional suffix for function literals. Generic functions and methods of generic types
+// include their type arguments, which are only known at runtime, so Push on *Stack[T]
+// is logged as e.g. Stack[int]_Push. For them, it also returns the pattern of the event
+// names with the type parameters in place of the type arguments, e.g. Stack[T]_Push.
+func (a *Annotator) eventName(f *ast.FuncDecl, packageName string, suffix string) (string, string) {
+	prefix := ""
+	if a.config.ShowPackage {
+		prefix = packageName + separator
+	}
+
+	name := funcName(f)
+	isMethod := f.Recv != nil && len(f.Recv.List) > 0
+
+	var typeParams []string
+	if isMethod {
+		typeParams = recvTypeParams(f.Recv.List[0].Type)
+	} else {
+		typeParams = paramNames(f.Type.TypeParams)
+	}
+
+	typeArgs := make([]string, 0, len(typeParams))
+	for _, param := range typeParams {
+		// Blank type parameters cannot be referred to.
+		if param == "_" {
+			return strconv.Quote(prefix + name + suffix), ""
+		}
+		typeArgs = append(typeArgs, importName+".TypeName["+param+"]()")
+	}
+
+	if len(typeArgs) == 0 {
+		return strconv.Quote(prefix + name + suffix), ""
+	}
+
+	// The event name is split around the type arguments.
+	before, after := prefix+name+"[", "]"+suffix
+	if isMethod {
+		before, after = prefix+recvTypeName(f.Recv.List[0].Type)+"[", "]"+separator+f.Name.Name+suffix
+	}
+
+	expr := strconv.Quote(before) + " + " + strings.Join(typeArgs, ` + "," + `) + " + " + strconv.Quote(after)
+	return expr, before + strings.Join(typeParams, ",") + after
+}
+
+// closure identifies a function literal by its enclosing declaration and its position
+// among the literals of that declaration.
+type closure struct {
+	decl  *ast.FuncDecl
+	index int
+}
+
+// globalName is the enclosing name of function literals in package-level declarations.
+const globalName = "glob"
+
+// closureNames numbers the function literals of every top-level declaration in source
+// order, so that each literal gets a stable name such as Serve_func1.
+func closureNames(f *ast.File) map[*ast.FuncLit]closure {
+	closures := make(map[*ast.FuncLit]closure)
+	for _, decl := range f.Decls {
+		funcDecl, _ := decl.(*ast.FuncDecl)
+
+		index := 0
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok {
+				index++
+				closures[lit] = closure{decl: funcDecl, index: index}
+			}
+			return true
+		})
+	}
+
+	return closures
+}
+
+// suffix returns the part of the closure name appended to the enclosing function name.
+func (c closure) suffix() string {
+	return separator + "func" + strconv.Itoa(c.index)
+}
+
+// name returns the synthetic name of the closure.
+func (c closure) name() string {
+	return c.nameAs("")
+}
+
+// nameAs returns the synthetic name of the closure, based on the given name of the
+// enclosing declaration if it is not empty.
+func (c closure) nameAs(declName string) string {
+	switch {
+	case declName != "":
+		return declName + c.suffix()
+	case c.decl == nil:
+		return globalName + c.suffix()
+	}
+	return funcName(c.decl) + c.suffix()
+}
+
+// closureEventName returns the Go expression naming the events of a function literal
+// and their pattern as returned by eventName, based on the given name of the enclosing
+// declaration if it is not empty.
+func (a *Annotator) closureEventName(c closure, packageName string, declName string) (string, string) {
+	if c.decl != nil && declName == "" {
+		return a.eventName(c.decl, packageName, c.suffix())
+	}
+
+	return a.staticEventName(c.nameAs(declName), packageName), ""
+}
+
+// staticEventName returns the Go expression naming the events of a function whose name
+// is known at annotation time.
+func (a *Annotator) staticEventName(name string, packageName string) string {
+	if a.config.ShowPackage {
+		name = packageName + separator + name
+	}
+	return strconv.Quote(name)
+}
@@ -0,0 +1,922 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	config := &Options{
+		ImportPath: "github.com/test/log",
+	}
+
+	annotator, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if annotator == nil {
+		t.Fatal("NewAnnotator returned nil")
+	}
+
+	if annotator.config != config {
+		t.Error("Annotator config not set correctly")
+	}
+
+	if annotator.fset == nil {
+		t.Error("FileSet not initialized")
+	}
+
+	if annotator.enterTemplate == nil {
+		t.Error("Enter template not initialized")
+	}
+
+	if annotator.leaveTemplate == nil {
+		t.Error("Leave template not initialized")
+	}
+}
+
+func TestAnnotateSource(t *testing.T) {
+	config := &Options{
+		ImportPath: "github.com/test/log",
+	}
+
+	annotator, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+import "fmt"
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func main() {
+	result := Add(5, 10)
+	fmt.Printf("Result: %d\n", result)
+}`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+
+	// Check that the log import was added
+	if !strings.Contains(resultStr, `__log "github.com/test/log"`) {
+		t.Error("Log import not added correctly")
+	}
+
+	// Check that function instrumentation was added
+	if !strings.Contains(resultStr, "__traceID := __log.ID()") {
+		t.Error("Function entry instrumentation not added")
+	}
+
+	if !strings.Contains(resultStr, "LogEnter") {
+		t.Error("LogEnter call not added")
+	}
+
+	if !strings.Contains(resultStr, "LogLeave") {
+		t.Error("LogLeave call not added")
+	}
+}
+
+func TestAnnotateSourceExportedOnly(t *testing.T) {
+	config := &Options{
+		ImportPath:   "github.com/test/log",
+		ExportedOnly: true,
+	}
+
+	annotator, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func privateFunc() {
+	// This should not be instrumented
+}`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+
+	// Add should be instrumented (exported) - check for LogEnter instead
+	if !strings.Contains(resultStr, "LogEnter") || !strings.Contains(resultStr, "Add") {
+		t.Error("Exported function not instrumented")
+	}
+
+	// Count occurrences to ensure only one function is instrumented
+	logEnterCount := strings.Count(resultStr, "LogEnter")
+	logLeaveCount := strings.Count(resultStr, "LogLeave")
+	if logEnterCount != 1 || logLeaveCount != 1 {
+		t.Errorf("Expected 1 LogEnter and 1 LogLeave for Add function, got %d LogEnter and %d LogLeave", logEnterCount, logLeaveCount)
+	}
+
+	// Verify privateFunc was not instrumented by checking it doesn't have logging calls
+	privateFuncLines := strings.Split(resultStr, "\n")
+	inPrivateFunc := false
+	for _, line := range privateFuncLines {
+		if strings.Contains(line, "func privateFunc()") {
+			inPrivateFunc = true
+		} else if inPrivateFunc && strings.Contains(line, "func ") {
+			inPrivateFunc = false
+		} else if inPrivateFunc && (strings.Contains(line, "LogEnter") || strings.Contains(line, "LogLeave")) {
+			t.Error("Private function was incorrectly instrumented")
+			break
+		}
+	}
+}
+
+func TestAnnotateSourceFilter(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Closures:   true,
+		Include:    []string{"^Conn_"},
+		Exclude:    []string{"_Close$"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+type Conn struct{}
+
+func (c *Conn) Read(p []byte) {
+	go func() {}()
+}
+
+func (c *Conn) Close() {}
+
+func Dial() {}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	for _, name := range []string{"Conn_Read", "Conn_Read_func1"} {
+		if !strings.Contains(resultStr, `"`+name+`"`) {
+			t.Errorf("Function %s was not instrumented", name)
+		}
+	}
+	for _, name := range []string{"Conn_Close", "Dial"} {
+		if strings.Contains(resultStr, `"`+name+`"`) {
+			t.Errorf("Function %s was instrumented", name)
+		}
+	}
+
+	var funcNames []string
+	for _, fn := range annotator.Manifest() {
+		funcNames = append(funcNames, fn.Name)
+	}
+	if len(funcNames) != 2 || funcNames[0] != "main_Conn_Read_func1" || funcNames[1] != "main_Conn_Read" {
+		t.Errorf("Rules do not match the instrumented functions: %v", funcNames)
+	}
+}
+
+func TestAnnotateSourceDirectives(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath:  "github.com/test/log",
+		ShowPackage: true,
+		Closures:    true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+//annotate:skip
+func Hidden() {
+	go func() {}()
+}
+
+// Seal encrypts msg.
+//
+//annotate:redact key,nonce
+//annotate:name Encrypt
+func Seal(key, nonce []byte, msg string) []byte {
+	return nil
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if strings.Count(resultStr, "__traceID := __log.ID()") != 1 {
+		t.Error("Skipped function or its function literals were instrumented")
+	}
+	if !strings.Contains(resultStr, `__log.LogEnter(__traceID, "main_Encrypt", []any{__log.Redacted, __log.Redacted, msg})`) {
+		t.Errorf("Directives were not applied:\n%s", resultStr)
+	}
+
+	manifest := annotator.Manifest()
+	if len(manifest) != 1 || manifest[0].Name != "main_Encrypt" || strings.Join(manifest[0].ArgNames(), ", ") != "key, nonce, msg" {
+		t.Errorf("Unexpected manifest %v", manifest)
+	}
+
+	stripped, err := annotator.StripSource("test.go", result)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+	if string(stripped) != testCode {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+
+	_, err = annotator.AnnotateSource("test.go", []byte("package main\n\n//annotate:redact secret\nfunc Seal(key []byte) {}\n"))
+	if err == nil || !strings.Contains(err.Error(), "test.go:3:1") {
+		t.Errorf("Expected a positioned directive error, got %v", err)
+	}
+}
+
+func TestAnnotateSourceUnnamedParams(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+type T struct{}
+
+func (T) F(_ int, s string) {}
+
+func (_ *T) G(int, string) (_ int, err error) {
+	return 0, nil
+}
+
+func H(x int) (result int) {
+	result = x
+	return
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	for _, expected := range []string{
+		"func (__recv T) F(__blank0 int, s string) {",
+		`__log.LogEnter(__traceID, "T_F", []any{__recv, __blank0, s})`,
+		"func (__blankRecv *T) G(__arg0 int, __arg1 string) (__blankRes0 int, err error) {",
+		`[]any{__blankRecv, __arg0, __arg1}, []any{__blankRes0, err})`,
+		// A result whose name starts with "res" is still a named result.
+		"result = func() (result int) {",
+	} {
+		if !strings.Contains(resultStr, expected) {
+			t.Errorf("Annotated source does not contain %q:\n%s", expected, resultStr)
+		}
+	}
+
+	args := make(map[string]string)
+	for _, fn := range annotator.Manifest() {
+		args[fn.Name] = strings.Join(fn.ArgNames(), ", ")
+	}
+	if args["main_T_F"] != "__recv, __blank0, s" || args["main_T_G"] != "__blankRecv, __arg0, __arg1" {
+		t.Errorf("Rules do not cover every argument: %v", args)
+	}
+}
+
+func TestParamNames(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name:     "simple parameters",
+			code:     "func test(a int, b string)",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "no parameters",
+			code:     "func test()",
+			expected: []string{},
+		},
+		{
+			name:     "grouped parameters",
+			code:     "func test(a, b int, c string)",
+			expected: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// This would require more complex AST parsing setup
+			// Simplified test for basic functionality
+			if len(tc.expected) == 0 {
+				// Test empty case
+				result := paramNames(nil)
+				if len(result) != 0 {
+					t.Errorf("Expected empty result, got %v", result)
+				}
+			}
+		})
+	}
+}
+
+// parseFuncDecl parses a single function declaration.
+func parseFuncDecl(t *testing.T, code string) *ast.FuncDecl {
+	t.Helper()
+
+	f, err := parser.ParseFile(token.NewFileSet(), "test.go", "package main\n\n"+code, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", code, err)
+	}
+
+	return f.Decls[0].(*ast.FuncDecl)
+}
+
+func TestFuncName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "simple function",
+			code:     "func Add() {}",
+			expected: "Add",
+		},
+		{
+			name:     "method with pointer receiver",
+			code:     "func (m *MyStruct) Method() {}",
+			expected: "MyStruct_Method",
+		},
+		{
+			name:     "method with value receiver",
+			code:     "func (m MyStruct) Method() {}",
+			expected: "MyStruct_Method",
+		},
+		{
+			name:     "method with generic receiver",
+			code:     "func (s *Stack[T]) Push(v T) {}",
+			expected: "Stack_Push",
+		},
+		{
+			name:     "method with multiple type parameters",
+			code:     "func (p Pair[K, V]) Get() {}",
+			expected: "Pair_Get",
+		},
+		{
+			name:     "generic function",
+			code:     "func Map[T, U any](xs []T) {}",
+			expected: "Map",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := funcName(parseFuncDecl(t, tc.code)); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestEventName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected string
+		pattern  string
+	}{
+		{
+			name:     "simple function",
+			code:     "func Add() {}",
+			expected: `"pkg_Add"`,
+		},
+		{
+			name:     "method with generic receiver",
+			code:     "func (s *Stack[T]) Push(v T) {}",
+			expected: `"pkg_Stack[" + __log.TypeName[T]() + "]_Push"`,
+			pattern:  "pkg_Stack[T]_Push",
+		},
+		{
+			name:     "method with blank type parameter",
+			code:     "func (p *Pair[_, V]) Get() {}",
+			expected: `"pkg_Pair_Get"`,
+		},
+		{
+			name:     "generic function",
+			code:     "func Map[T, U any](xs []T) {}",
+			expected: `"pkg_Map[" + __log.TypeName[T]() + "," + __log.TypeName[U]() + "]"`,
+			pattern:  "pkg_Map[T,U]",
+		},
+	}
+
+	annotator, err := New(&Options{ShowPackage: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, pattern := annotator.eventName(parseFuncDecl(t, tc.code), "pkg", "")
+			if result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+			if pattern != tc.pattern {
+				t.Errorf("Expected pattern %q, got %q", tc.pattern, pattern)
+			}
+		})
+	}
+}
+
+func TestAnnotateSourceGeneric(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Closures:   true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+func (s *Stack[T]) Each(f func(T)) {
+	func() {}()
+}
+
+func Map[T, U any](xs []T, f func(T) U) []U {
+	return nil
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if !strings.Contains(resultStr, "func Map[T, U any](xs []T, f func(T) U) []U {") {
+		t.Error("Type parameters of generic function were not preserved")
+	}
+	if !strings.Contains(resultStr, `"Stack["+__log.TypeName[T]()+"]_Push"`) {
+		t.Error("Generic method event name does not include type arguments")
+	}
+
+	patterns := map[string]string{
+		"main_Stack_Push":       "Stack[T]_Push",
+		"main_Stack_Each":       "Stack[T]_Each",
+		"main_Stack_Each_func1": "Stack[T]_Each_func1",
+		"main_Map":              "Map[T,U]",
+	}
+	manifest := annotator.Manifest()
+	if len(manifest) != len(patterns) {
+		t.Errorf("Expected %d functions, got %+v", len(patterns), manifest)
+	}
+	for _, fn := range manifest {
+		if pattern, ok := patterns[fn.Name]; !ok || fn.EventPattern != pattern {
+			t.Errorf("Unexpected rule name %q or event pattern %q", fn.Name, fn.EventPattern)
+		}
+	}
+
+	// The rules are triggered by the runtime events of each instantiation.
+	theory := GenerateTheory(manifest)
+	for _, rule := range []string{
+		"rule Main_Stack_Push [trigger=[<Stack[T]_Push(s, v), <>>]]",
+		"rule Main_Stack_Push_Panic [trigger=[<Stack[T]_Push_Panic(s, v), <value>>]]",
+		"rule Main_Stack_Each_func1 [trigger=[<Stack[T]_Each_func1(), <>>]]",
+		"rule Main_Map [trigger=[<Map[T,U](xs, f), <res1>>]]",
+	} {
+		if !strings.Contains(theory, rule) {
+			t.Errorf("Theory does not contain %q:\n%s", rule, theory)
+		}
+	}
+}
+
+func TestAnnotateSourcePanic(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Stack:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+func Divide(a, b int) (int, error) {
+	return a / b, nil
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if !strings.Contains(resultStr, `__log.LogPanic(__traceID, "Divide", []any{a, b}, __panic, true)`) {
+		t.Error("LogPanic call not added")
+	}
+	if !strings.Contains(resultStr, "panic(__panic)") {
+		t.Error("Panic is not raised again")
+	}
+
+	// The results must be declared and LogLeave deferred before the original body runs.
+	decl := strings.Index(resultStr, "res2 error")
+	deferred := strings.Index(resultStr, "defer func()")
+	call := strings.Index(resultStr, "res1, res2 = func() (int, error)")
+	if decl < 0 || deferred < 0 || call < 0 || !(decl < deferred && deferred < call) {
+		t.Errorf("LogLeave is not deferred before the original body:\n%s", resultStr)
+	}
+
+	theory := GenerateTheory(annotator.Manifest())
+	if !strings.Contains(theory, "rule Main_Divide_Panic [trigger=[<main_Divide_Panic(a, b), <value, stack>>]]") {
+		t.Errorf("Theory does not contain the panic rule:\n%s", theory)
+	}
+}
+
+func TestAnnotateSourceTiming(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Timing:     true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+func Add(a, b int) int {
+	return a + b
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if !strings.Contains(resultStr, "__traceStart := __log.Now()") {
+		t.Error("Start time is not taken")
+	}
+	if !strings.Contains(resultStr, `__log.LogLeaveTimed(__traceID, "Add", []any{a, b}, []any{res1}, __traceStart)`) {
+		t.Error("LogLeaveTimed call not added")
+	}
+	if strings.Contains(resultStr, "__log.LogLeave(") {
+		t.Error("Untimed LogLeave call added")
+	}
+}
+
+func TestAnnotateSourceDefer(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Defer:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Divide(a, b int) (q int, err error) {
+	q = a / b
+	return
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if strings.Contains(resultStr, "= func()") {
+		t.Errorf("Body is wrapped in a closure:\n%s", resultStr)
+	}
+	if !strings.Contains(resultStr, "func Add(a, b int) (__res0 int) {") {
+		t.Error("Unnamed result is not named")
+	}
+	if !strings.Contains(resultStr, `__log.LogLeave(__traceID, "Add", []any{a, b}, []any{__res0})`) {
+		t.Error("Named result is not logged")
+	}
+	if !strings.Contains(resultStr, "\t}()\n\treturn a + b\n}") || !strings.Contains(resultStr, "\t}()\n\tq = a / b\n\treturn\n}") {
+		t.Errorf("Body is not kept in place:\n%s", resultStr)
+	}
+}
+
+func TestAnnotateSourceComments(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Defer:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+// Add adds two numbers.
+func Add(a, b int) int {
+	// The sum.
+	return a + b // not checked for overflow
+}
+
+// Log logs a message.
+func Log(msg string) {
+	/* Printed
+	   as is. */
+	println(msg)
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	// Apart from the signature of Add, the original lines are kept in order and only
+	// instrumentation is inserted between them.
+	expected := strings.Split(strings.Replace(testCode, "int) int {", "int) (__res0 int) {", 1), "\n")
+	lines := strings.Split(string(result), "\n")
+	for _, line := range lines {
+		if len(expected) > 0 && line == expected[0] {
+			expected = expected[1:]
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("Line %q is missing or out of place:\n%s", expected[0], result)
+	}
+}
+
+func TestDeferCallerDepth(t *testing.T) {
+	testCode := `package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func Caller() (string, bool) {
+	pc, _, _, ok := runtime.Caller(1)
+	return runtime.FuncForPC(pc).Name(), ok
+}
+
+func main() {
+	fmt.Println(Caller())
+}
+`
+
+	output := runAnnotated(t, Options{Defer: true}, testCode)
+	if output != "main.main true" {
+		t.Errorf("Expected caller main.main, got %q", output)
+	}
+}
+
+func TestAnnotateSourceFormatLength(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath:   "github.com/test/log",
+		FormatLength: 64,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := annotator.AnnotateSource("test.go", []byte("package main\n\nfunc main() {}\n"))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+	if !strings.HasSuffix(string(result), "\nfunc init() {\n\t__log.SetFormatLength(64)\n}\n") {
+		t.Errorf("Format length is not passed to the log package:\n%s", result)
+	}
+
+	// Files without instrumented functions do not import the log package.
+	result, err = annotator.AnnotateSource("test.go", []byte("package main\n\nvar x = 1\n"))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+	if strings.Contains(string(result), "SetFormatLength") {
+		t.Errorf("Format length is set in a file without instrumentation:\n%s", result)
+	}
+}
+
+func TestAnnotateSourceClosures(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath:  "github.com/test/log",
+		ShowPackage: true,
+		Closures:    true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+import "sync"
+
+var handler = func(x int) int {
+	return x
+}
+
+func Serve(n int) error {
+	var once sync.Once
+	once.Do(func() {
+		go func(i int) {
+			_ = i
+		}(n)
+	})
+	return nil
+}
+`
+
+	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	for _, name := range []string{"main_glob_func1", "main_Serve", "main_Serve_func1", "main_Serve_func2"} {
+		if !strings.Contains(resultStr, `"`+name+`"`) {
+			t.Errorf("Function %s was not instrumented", name)
+		}
+	}
+
+	if count := strings.Count(resultStr, "__traceID := __log.ID()"); count != 4 {
+		t.Errorf("Expected 4 instrumented functions, got %d", count)
+	}
+
+	var ruleNames []string
+	for _, fn := range annotator.Manifest() {
+		ruleNames = append(ruleNames, fn.Name)
+	}
+	if !strings.Contains(GenerateTheory(annotator.Manifest()), "main_Serve_func2(i)") {
+		t.Errorf("Theory does not contain closure rules: %v", ruleNames)
+	}
+}
+
+func TestAnnotateFile(t *testing.T) {
+	// Create temporary test file
+	testContent := `package main
+
+import "fmt"
+
+func Hello() {
+	fmt.Println("Hello, World!")
+}
+
+func main() {
+	Hello()
+}`
+
+	tmpFile, err := os.CreateTemp("", "test_*.go")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write([]byte(testContent)); err != nil {
+		t.Fatalf("Failed to write test content: %v", err)
+	}
+	tmpFile.Close()
+
+	config := &Options{
+		ImportPath: "github.com/test/log",
+	}
+
+	annotator, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := annotator.AnnotateFile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("AnnotateFile failed: %v", err)
+	}
+
+	if result.Filename != tmpFile.Name() || string(result.Source) != testContent || !result.Changed() {
+		t.Errorf("Unexpected result for %s: %+v", tmpFile.Name(), result)
+	}
+
+	output := string(result.Output)
+
+	// Verify instrumentation was added
+	if !strings.Contains(output, "LogEnter") {
+		t.Error("LogEnter not found in output")
+	}
+
+	if !strings.Contains(output, "LogLeave") {
+		t.Error("LogLeave not found in output")
+	}
+
+	if len(result.Functions) != 2 || result.Functions[0].Name != "main_Hello" {
+		t.Errorf("Unexpected functions %v", result.Functions)
+	}
+}
+
+// Benchmark tests.
+func BenchmarkAnnotateSource(b *testing.B) {
+	config := &Options{
+		ImportPath: "github.com/test/log",
+	}
+
+	annotator, err := New(config)
+	if err != nil {
+		b.Fatalf("New failed: %v", err)
+	}
+
+	testCode := []byte(`package main
+
+import "fmt"
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Multiply(a, b int) int {
+	return a * b
+}
+
+func Calculate(x, y int) (int, int) {
+	sum := Add(x, y)
+	product := Multiply(x, y)
+	return sum, product
+}
+
+func main() {
+	sum, product := Calculate(5, 10)
+	fmt.Printf("Sum: %d, Product: %d\n", sum, product)
+}`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := annotator.AnnotateSource("bench.go", testCode)
+		if err != nil {
+			b.Fatalf("AnnotateSource failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkNewAnnotator(b *testing.B) {
+	config := &Options{
+		ImportPath: "github.com/test/log",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		annotator, err := New(config)
+		if err != nil {
+			b.Fatalf("New failed: %v", err)
+		}
+		_ = annotator
+	}
+}
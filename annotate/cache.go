@@ -0,0 +1,228 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// modulePath is the path of the module of the annotator, whose version is part of the
+// cache keys.
+const modulePath = "github.com/specmon/go-annotate"
+
+// cacheEntry is a processed file stored in the cache directory, with the part of the
+// summary it contributed.
+type cacheEntry struct {
+	Output       []byte
+	Base         []byte
+	Generated    []bool
+	FormatLength int
+	Functions    []Function
+	Diagnostics  []cachedError
+	Instrumented int
+	Skipped      int
+	Failed       int
+}
+
+// cachedError is a stored FuncError. Only the message of the underlying error is kept.
+type cachedError struct {
+	Pos  token.Position
+	Func string
+	Err  string
+}
+
+// version identifies the build of the annotator. Released builds are identified by
+// their module version, development builds by the hash of their executable.
+var version = sync.OnceValues(func() (string, error) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mod := &info.Main
+		if mod.Path != modulePath {
+			mod = nil
+			for _, dep := range info.Deps {
+				if dep.Path == modulePath && dep.Replace == nil {
+					mod = dep
+				}
+			}
+		}
+		if mod != nil && mod.Version != "" && mod.Version != "(devel)" && !strings.HasSuffix(mod.Version, "+dirty") {
+			return mod.Version, nil
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "exe-" + hex.EncodeToString(h.Sum(nil)), nil
+})
+
+// cacheKey returns the key of a file in the cache. It covers the content of the file
+// and everything else its processing depends on: the annotator build, the options,
+// the name and package of the file and, in type-checked mode, its signatures.
+func (a *Annotator) cacheKey(file string, src []byte) (string, error) {
+	v, err := version()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the annotator build: %w", err)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	opts := *a.config
+	opts.FileOptions, opts.Jobs, opts.CacheDir = nil, 0, ""
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%#v\n%q %q %q\n", v, opts, file, abs, a.packagePathFor(file))
+	a.typesFor(file).digest(h)
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachePath returns the location of a cache entry.
+func (a *Annotator) cachePath(key string) string {
+	return filepath.Join(a.config.CacheDir, key[:2], key)
+}
+
+// loadCached fills r from the cache entry with the given key and records its functions
+// and summary. Missing or unreadable entries are not found.
+func (a *Annotator) loadCached(key string, r *Result) bool {
+	data, err := os.ReadFile(a.cachePath(key))
+	if err != nil {
+		return false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return false
+	}
+
+	r.Output, r.base, r.generated, r.formatLength = e.Output, e.Base, e.Generated, e.FormatLength
+	r.Functions, r.Cached = e.Functions, true
+	for _, d := range e.Diagnostics {
+		r.Diagnostics = append(r.Diagnostics, &FuncError{Pos: d.Pos, Func: d.Func, Err: errors.New(d.Err)})
+	}
+
+	a.functions = append(a.functions, r.Functions...)
+	a.summary.add(Summary{Instrumented: e.Instrumented, Skipped: e.Skipped, Failed: e.Failed, Errors: r.Diagnostics})
+	return true
+}
+
+// storeCached stores a processed file and its part of the summary under the given key.
+// The entry is written to a temporary file first, so that concurrent runs never see
+// a partial entry.
+func (a *Annotator) storeCached(key string, r *Result, summary Summary) error {
+	e := cacheEntry{
+		Output:       r.Output,
+		Base:         r.base,
+		Generated:    r.generated,
+		FormatLength: r.formatLength,
+		Functions:    r.Functions,
+		Instrumented: summary.Instrumented,
+		Skipped:      summary.Skipped,
+		Failed:       summary.Failed,
+	}
+	for _, err := range r.Diagnostics {
+		// Entries are only stored if their diagnostics can be restored.
+		var funcErr *FuncError
+		if !errors.As(err, &funcErr) {
+			return nil
+		}
+		e.Diagnostics = append(e.Diagnostics, cachedError{Pos: funcErr.Pos, Func: funcErr.Func, Err: funcErr.Err.Error()})
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	path := a.cachePath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// digest writes the signatures of the functions to w, with the conversions of their
+// types, which determine the instrumentation in type-checked mode.
+func (ti *typeInfo) digest(w io.Writer) {
+	if ti == nil {
+		return
+	}
+
+	names := make([]string, 0, len(ti.funcs))
+	for name := range ti.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	qualifier := types.RelativeTo(ti.pkg)
+	for _, name := range names {
+		sig := ti.funcs[name]
+		vars := append(tupleVars(sig.Params()), tupleVars(sig.Results())...)
+		if recv := sig.Recv(); recv != nil {
+			vars = append(vars, recv)
+		}
+
+		fmt.Fprintf(w, "%s", name)
+		for _, v := range vars {
+			fmt.Fprintf(w, " %s:%s", types.TypeString(v.Type(), qualifier), formatConversion(v.Type()))
+		}
+		fmt.Fprintln(w)
+	}
+}
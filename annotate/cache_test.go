@@ -0,0 +1,133 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\nfunc Add(a, b int) int { return a + b }\n\n//annotate:redact missing\nfunc Bad() {}\n"
+
+	annotate := func(opts Options) (*Result, Summary) {
+		t.Helper()
+		opts.ImportPath = "github.com/test/log"
+		opts.KeepGoing = true
+		opts.CacheDir = cacheDir
+		annotator, err := New(&opts)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		r, err := annotator.AnnotateFile(file)
+		if err != nil {
+			t.Fatalf("AnnotateFile failed: %v", err)
+		}
+		return r, annotator.Summary()
+	}
+
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, firstSummary := annotate(Options{})
+	if first.Cached {
+		t.Fatalf("First run was taken from the empty cache")
+	}
+
+	second, secondSummary := annotate(Options{})
+	if !second.Cached {
+		t.Fatalf("Unchanged file was not taken from the cache")
+	}
+	if !bytes.Equal(second.Output, first.Output) || !bytes.Equal(second.base, first.base) || !reflect.DeepEqual(second.generated, first.generated) || !reflect.DeepEqual(second.Functions, first.Functions) {
+		t.Errorf("Cached result differs from the processed one")
+	}
+	if len(second.Diagnostics) != 1 || second.Diagnostics[0].Error() != first.Diagnostics[0].Error() {
+		t.Errorf("Cached diagnostics %v, want %v", second.Diagnostics, first.Diagnostics)
+	}
+	if secondSummary.String() != firstSummary.String() || len(secondSummary.Errors) != 1 {
+		t.Errorf("Cached summary %v, want %v", secondSummary, firstSummary)
+	}
+
+	// Options that do not change the output share the entries.
+	if r, _ := annotate(Options{Jobs: 4}); !r.Cached {
+		t.Errorf("Number of jobs changed the cache key")
+	}
+	if r, _ := annotate(Options{ShowReturn: true}); r.Cached {
+		t.Errorf("Changed options were taken from the cache")
+	}
+
+	if err := os.WriteFile(file, []byte(src+"\nfunc Sub(a, b int) int { return a - b }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := annotate(Options{}); r.Cached || len(r.Functions) != 2 {
+		t.Errorf("Changed file was taken from the cache: %v", r.Functions)
+	}
+}
+
+func TestCacheTypes(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":   "module example.com/svc\n\ngo 1.22\n",
+		"svc.go":   "package svc\n\nfunc Get(id UserID) {}\n",
+		"types.go": "package svc\n\ntype UserID int64\n",
+	})
+	cacheDir := t.TempDir()
+
+	annotate := func() *Result {
+		t.Helper()
+		annotator, err := New(&Options{ImportPath: "github.com/test/log", Types: true, CacheDir: cacheDir})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		pkgs, err := annotator.LoadPackages(dir, ".")
+		if err != nil {
+			t.Fatalf("LoadPackages failed: %v", err)
+		}
+		results, err := annotator.AnnotatePackages(pkgs...)
+		if err != nil {
+			t.Fatalf("AnnotatePackages failed: %v", err)
+		}
+		for _, r := range results {
+			if filepath.Base(r.Filename) == "svc.go" {
+				return r
+			}
+		}
+		t.Fatalf("No result for svc.go")
+		return nil
+	}
+
+	if r := annotate(); r.Cached || !bytes.Contains(r.Output, []byte("int64(id)")) {
+		t.Fatalf("Unexpected first run, cached %v:\n%s", r.Cached, r.Output)
+	}
+	if r := annotate(); !r.Cached {
+		t.Errorf("Unchanged package was not taken from the cache")
+	}
+
+	// The conversion of the argument depends on another file of the package.
+	if err := os.WriteFile(filepath.Join(dir, "types.go"), []byte("package svc\n\ntype UserID string\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r := annotate(); r.Cached || !bytes.Contains(r.Output, []byte("string(id)")) {
+		t.Errorf("Changed types were taken from the cache:\n%s", r.Output)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// directivePrefix starts the comments in the doc of a function declaration that
+// control its instrumentation.
+const directivePrefix = "//annotate:"
+
+// redactedValue is logged in place of redacted arguments and results.
+const redactedValue = importName + ".Redacted"
+
+// directives holds the instrumentation policy of a function declaration:
+//
+//	//annotate:skip           do not instrument the function and its function literals
+//	//annotate:redact a,b     log a placeholder instead of the values of a and b
+//	//annotate:name Foo       use Foo instead of the qualified name for events and rules
+type directives struct {
+	skip   bool
+	redact map[string]bool
+	name   string
+	err    error
+}
+
+// parseDirectives parses the directives in the doc comment of a function declaration.
+func parseDirectives(fset *token.FileSet, decl *ast.FuncDecl) (directives, error) {
+	var d directives
+	if decl.Doc == nil {
+		return d, nil
+	}
+
+	var errs []error
+	for _, comment := range decl.Doc.List {
+		text, ok := strings.CutPrefix(comment.Text, directivePrefix)
+		if !ok {
+			continue
+		}
+
+		pos := fset.Position(comment.Pos())
+		verb, arg, _ := strings.Cut(text, " ")
+		arg = strings.TrimSpace(arg)
+		switch verb {
+		case "skip":
+			if arg != "" {
+				errs = append(errs, fmt.Errorf("%s: //annotate:skip takes no arguments", pos))
+			}
+			d.skip = true
+		case "redact":
+			if arg == "" {
+				errs = append(errs, fmt.Errorf("%s: //annotate:redact requires parameter names", pos))
+			}
+			known := knownNames(decl)
+			for _, name := range strings.Split(arg, ",") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+				if !known[name] {
+					errs = append(errs, fmt.Errorf("%s: //annotate:redact: %s has no parameter %s", pos, funcName(decl), name))
+					continue
+				}
+				if d.redact == nil {
+					d.redact = make(map[string]bool)
+				}
+				d.redact[name] = true
+			}
+		case "name":
+			if arg == "" || strings.ContainsAny(arg, " \t") {
+				errs = append(errs, fmt.Errorf("%s: //annotate:name requires a single name", pos))
+				continue
+			}
+			d.name = arg
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown directive %s%s", pos, directivePrefix, verb))
+		}
+	}
+
+	return d, errors.Join(errs...)
+}
+
+// knownNames returns the names of the receiver, parameters and named results of a function.
+func knownNames(decl *ast.FuncDecl) map[string]bool {
+	known := make(map[string]bool)
+	for _, list := range []*ast.FieldList{decl.Recv, decl.Type.Params, decl.Type.Results} {
+		for _, name := range paramNames(list) {
+			known[name] = true
+		}
+	}
+
+	return known
+}
+
+// fileDirectives parses the directives of all function declarations in a file. The
+// directives of a function with invalid directives only hold the error.
+func fileDirectives(fset *token.FileSet, f *ast.File) map[*ast.FuncDecl]directives {
+	dirs := make(map[*ast.FuncDecl]directives)
+	for _, decl := range f.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		d, err := parseDirectives(fset, funcDecl)
+		if err != nil {
+			d = directives{err: err}
+		}
+		dirs[funcDecl] = d
+	}
+
+	return dirs
+}
+
+// values returns the expressions logged for the given names, replacing redacted ones
+// with a placeholder and applying the given type conversions to the others.
+func (d directives) values(names []string, conversions map[string]string) []string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		switch {
+		case d.redact[name]:
+			values[i] = redactedValue
+		case conversions[name] != "":
+			values[i] = conversions[name] + "(" + name + ")"
+		default:
+			values[i] = name
+		}
+	}
+
+	return values
+}
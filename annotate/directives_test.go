@@ -0,0 +1,96 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected directives
+		err      string
+	}{
+		{
+			name: "no directives",
+			code: "// Seal encrypts.\nfunc Seal(key []byte) {}",
+		},
+		{
+			name:     "skip",
+			code:     "//annotate:skip\nfunc Seal(key []byte) {}",
+			expected: directives{skip: true},
+		},
+		{
+			name:     "redact and name",
+			code:     "// Seal encrypts.\n//\n//annotate:redact key, nonce\n//annotate:name Encrypt\nfunc (c *Cipher) Seal(key, nonce []byte) (out []byte) {}",
+			expected: directives{redact: map[string]bool{"key": true, "nonce": true}, name: "Encrypt"},
+		},
+		{
+			name:     "redact receiver and result",
+			code:     "//annotate:redact c,out\nfunc (c *Cipher) Seal(key []byte) (out []byte) {}",
+			expected: directives{redact: map[string]bool{"c": true, "out": true}},
+		},
+		{
+			name: "unknown parameter",
+			code: "//annotate:redact secret\nfunc Seal(key []byte) {}",
+			err:  "Seal has no parameter secret",
+		},
+		{
+			name: "unknown directive",
+			code: "//annotate:skipp\nfunc Seal(key []byte) {}",
+			err:  "unknown directive //annotate:skipp",
+		},
+		{
+			name: "name without argument",
+			code: "//annotate:name\nfunc Seal(key []byte) {}",
+			err:  "requires a single name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, "test.go", "package main\n\n"+tc.code, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("Failed to parse code: %v", err)
+			}
+
+			d, err := parseDirectives(fset, f.Decls[0].(*ast.FuncDecl))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDirectives failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(d, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// filter selects functions by regular expressions on their qualified names and files
+// by glob patterns on their base names. Empty include lists select everything.
+type filter struct {
+	include      []*regexp.Regexp
+	exclude      []*regexp.Regexp
+	includeFiles []string
+	excludeFiles []string
+}
+
+// newFilter compiles the function and file selection of a configuration.
+func newFilter(config *Options) (*filter, error) {
+	include, err := compilePatterns(config.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+
+	exclude, err := compilePatterns(config.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	for _, pattern := range append(append([]string(nil), config.IncludeFiles...), config.ExcludeFiles...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &filter{
+		include:      include,
+		exclude:      exclude,
+		includeFiles: config.IncludeFiles,
+		excludeFiles: config.ExcludeFiles,
+	}, nil
+}
+
+// compilePatterns compiles a list of regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, re)
+	}
+
+	return regexps, nil
+}
+
+// function reports whether a function is selected by its qualified name as returned
+// by funcName, e.g. Conn_Read, or by closure.name for function literals.
+func (f *filter) function(name string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+
+	return !matchAny(f.exclude, name)
+}
+
+// file reports whether a source file is selected by its base name, e.g. types_gen.go.
+func (f *filter) file(path string) bool {
+	base := filepath.Base(path)
+	if len(f.includeFiles) > 0 && !matchAnyGlob(f.includeFiles, base) {
+		return false
+	}
+
+	return !matchAnyGlob(f.excludeFiles, base)
+}
+
+// matchAny reports whether any of the regular expressions matches s.
+func matchAny(regexps []*regexp.Regexp, s string) bool {
+	for _, re := range regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchAnyGlob reports whether any of the glob patterns matches name.
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
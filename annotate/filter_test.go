@@ -0,0 +1,98 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"testing"
+)
+
+func TestFilterFunction(t *testing.T) {
+	f, err := newFilter(&Options{
+		Include: []string{"^Conn_", "^handshake"},
+		Exclude: []string{"_Close$"},
+	})
+	if err != nil {
+		t.Fatalf("newFilter failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{"Conn_Read", true},
+		{"Conn_Close", false},
+		{"handshake_func1", true},
+		{"handshakeClient", true},
+		{"Listener_Accept", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if selected := f.function(tc.name); selected != tc.expected {
+				t.Errorf("Expected %v for %s, got %v", tc.expected, tc.name, selected)
+			}
+		})
+	}
+}
+
+func TestFilterFile(t *testing.T) {
+	f, err := newFilter(&Options{
+		ExcludeFiles: []string{"*_gen.go", "*_test.go"},
+	})
+	if err != nil {
+		t.Fatalf("newFilter failed: %v", err)
+	}
+
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{"conn.go", true},
+		{"internal/conn/conn.go", true},
+		{"internal/conn/types_gen.go", false},
+		{"/abs/conn_test.go", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if selected := f.file(tc.path); selected != tc.expected {
+				t.Errorf("Expected %v for %s, got %v", tc.expected, tc.path, selected)
+			}
+		})
+	}
+
+	f, err = newFilter(&Options{
+		IncludeFiles: []string{"conn*.go"},
+	})
+	if err != nil {
+		t.Fatalf("newFilter failed: %v", err)
+	}
+	if !f.file("pkg/conn_unix.go") || f.file("pkg/listener.go") {
+		t.Error("Include file pattern not applied")
+	}
+}
+
+func TestNewFilterInvalid(t *testing.T) {
+	if _, err := newFilter(&Options{Include: []string{"(*Conn)_Read"}}); err == nil {
+		t.Error("Expected an error for an invalid regular expression")
+	}
+
+	if _, err := newFilter(&Options{ExcludeFiles: []string{"[_gen.go"}}); err == nil {
+		t.Error("Expected an error for an invalid glob")
+	}
+}
@@ -15,7 +15,7 @@
 // You should have received a copy of the MIT License
 // along with this program. If not, see <https://opensource.org/licenses/MIT>.
 
-package main
+package annotate
 
 import (
 	"bytes"
@@ -26,23 +26,34 @@ import (
 const (
 	theoryTmpl = `theory {{.theoryName}}
 begin
-{{range .rules}}
-rule {{makeRuleName .ruleName}} [trigger=[<{{.funcName}}({{.args}}), <{{.results}}>>]]:
+{{range .functions}}
+{{- with .EventPattern}}
+// The type parameters in {{.}} stand for the type arguments of each instantiation.{{end}}
+{{- with .Signature}}
+// {{.}}{{end}}
+rule {{makeRuleName .Name}} [trigger=[<{{or .EventPattern .Name}}({{join .ArgNames}}), <{{join .ResultNames}}>>]]:
+  [ ] --[ ]-> [ ]
+
+rule {{makeRuleName .Name}}_Panic [trigger=[<{{or .EventPattern .Name}}_Panic({{join .ArgNames}}), <value{{if .Stack}}, stack{{end}}>>]]:
   [ ] --[ ]-> [ ]
 {{end}}
 end`
 )
 
-func GenerateTheory(rules []map[string]string) string {
+// GenerateTheory renders the monitoring rules of the instrumented functions.
+func GenerateTheory(functions []Function) string {
 	funcMap := template.FuncMap{
 		"makeRuleName": convertFuncName,
+		"join": func(names []string) string {
+			return strings.Join(names, ", ")
+		},
 	}
 
 	template := template.Must(template.New("theory").Funcs(funcMap).Parse(theoryTmpl))
 
 	t := map[string]interface{}{
 		"theoryName": "Preprocess",
-		"rules":      rules,
+		"functions":  functions,
 	}
 
 	var buf bytes.Buffer
@@ -0,0 +1,275 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const lineDirectivePrefix = "//line "
+
+var lineDirectiveRe = regexp.MustCompile(`^//line (.+):[0-9]+$`)
+
+// anchor maps a line of the annotated output to a line of the original source.
+type anchor struct {
+	out  int
+	orig int
+}
+
+// sourceMap relates the lines of an annotated file to the lines of the original source.
+// The declarations and statements of the annotated source that were not generated
+// correspond one to one to those of the original syntax tree.
+type sourceMap struct {
+	filename  string
+	out       *ast.File
+	nodes     []ast.Node
+	generated []bool
+	anchors   []anchor
+}
+
+// generatedNodes marks the declarations and statements of an annotated file, in source
+// order, that start in the text inserted by the edits, as well as the added log import.
+func (a *Annotator) generatedNodes(annotated *ast.File, edits []edit) []bool {
+	// The inserted text in the edited source, with the edits sorted by applyEdits.
+	var inserted []edit
+	delta := 0
+	for _, e := range edits {
+		start := e.start + delta
+		inserted = append(inserted, edit{start: start, end: start + len(e.text)})
+		delta += len(e.text) - (e.end - e.start)
+	}
+
+	nodes := positionedNodes(annotated)
+	generated := make([]bool, len(nodes))
+	for i, node := range nodes {
+		offset := a.fset.File(node.Pos()).Offset(node.Pos())
+		generated[i] = inEdits(inserted, offset) || isLogImportDecl(node)
+	}
+
+	return generated
+}
+
+// newSourceMap maps the annotated source src of a file back to its original syntax tree
+// f. The source is the printed annotated syntax tree, whose generated declarations and
+// statements are marked, possibly followed by further declarations.
+func (a *Annotator) newSourceMap(filename string, f *ast.File, src []byte, generated []bool) (*sourceMap, error) {
+	out, err := parser.ParseFile(a.fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse annotated source: %w", err)
+	}
+
+	nodes := positionedNodes(out)
+	var outNodes []ast.Node
+	for i, node := range nodes {
+		if i >= len(generated) || !generated[i] {
+			outNodes = append(outNodes, node)
+		}
+	}
+
+	leading := leadingComments(a.fset, out, src)
+	origNodes := positionedNodes(f)
+	var anchors []anchor
+	for i := 0; i < len(origNodes) && i < len(outNodes); i++ {
+		orig, node := origNodes[i], outNodes[i]
+		if fmt.Sprintf("%T", orig) != fmt.Sprintf("%T", node) {
+			break
+		}
+
+		outLine := a.fset.PositionFor(node.Pos(), false).Line
+		origLine := a.fset.PositionFor(orig.Pos(), false).Line
+
+		// Comments belong to the node below them.
+		if line, ok := leading[outLine]; ok {
+			origLine -= outLine - line
+			outLine = line
+		}
+
+		anchors = append(anchors, anchor{out: outLine, orig: origLine})
+	}
+
+	sort.SliceStable(anchors, func(i, j int) bool {
+		return anchors[i].out < anchors[j].out
+	})
+
+	return &sourceMap{filename: filename, out: out, nodes: nodes, generated: generated, anchors: anchors}, nil
+}
+
+// inInstrumentation reports whether the innermost declaration or statement of the
+// annotated source that contains a position was generated.
+func (m *sourceMap) inInstrumentation(pos token.Pos) bool {
+	found := false
+	for i, node := range m.nodes {
+		if node.Pos() <= pos && pos < node.End() {
+			found = i < len(m.generated) && m.generated[i]
+		}
+	}
+
+	return found
+}
+
+// line returns the original line of a line of the annotated source.
+func (m *sourceMap) line(out int) int {
+	line := out
+	for _, an := range m.anchors {
+		if an.out > out {
+			break
+		}
+		line = an.orig + out - an.out
+	}
+
+	return line
+}
+
+// addLineDirectives inserts //line directives into the printed source of an annotated
+// file, so that the original declarations and statements keep their positions.
+func (a *Annotator) addLineDirectives(filename string, f *ast.File, src []byte, generated []bool) ([]byte, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := a.newSourceMap(filename, f, src, generated)
+	if err != nil {
+		return nil, err
+	}
+
+	inString := rawStringLines(a.fset, m.out)
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	directives := make(map[int]int)
+	delta := 0
+	for _, an := range m.anchors {
+		if an.out+delta == an.orig || inString[an.out] {
+			continue
+		}
+
+		directives[an.out] = an.orig
+		delta = an.orig - an.out
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%s:1\n", lineDirectivePrefix, path)
+	for i, line := range lines {
+		if orig, ok := directives[i+1]; ok {
+			fmt.Fprintf(&buf, "%s%s:%d\n", lineDirectivePrefix, path, orig)
+		}
+		buf.Write(line)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// positionedNodes returns the declarations and statements of a file in source order.
+func positionedNodes(f *ast.File) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n.(type) {
+		case ast.Decl, ast.Stmt:
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+
+	return nodes
+}
+
+// isLogImportDecl matches an import declaration of only the log package, which is added
+// to files without imports.
+func isLogImportDecl(node ast.Node) bool {
+	gen, ok := node.(*ast.GenDecl)
+	if !ok || gen.Tok != token.IMPORT {
+		return false
+	}
+
+	for _, spec := range gen.Specs {
+		if imp := spec.(*ast.ImportSpec); imp.Name == nil || imp.Name.Name != importName {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingComments maps the lines directly below comment groups that start their own
+// line, such as doc comments, to the first line of the comments.
+func leadingComments(fset *token.FileSet, f *ast.File, src []byte) map[int]int {
+	lines := make(map[int]int)
+	for _, c := range f.Comments {
+		start := fset.PositionFor(c.Pos(), false)
+		lineStart := start.Offset - (start.Column - 1)
+		if len(bytes.TrimSpace(src[lineStart:start.Offset])) > 0 {
+			continue
+		}
+
+		lines[fset.PositionFor(c.End(), false).Line+1] = start.Line
+	}
+
+	return lines
+}
+
+// inEdits reports whether an offset lies within the source replaced by one of the edits.
+func inEdits(edits []edit, offset int) bool {
+	for _, e := range edits {
+		if e.start <= offset && offset < e.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rawStringLines returns the lines that continue a multi-line raw string literal, in
+// front of which no directive can be inserted.
+func rawStringLines(fset *token.FileSet, f *ast.File) map[int]bool {
+	lines := make(map[int]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			start, end := fset.PositionFor(lit.Pos(), false).Line, fset.PositionFor(lit.End(), false).Line
+			for line := start + 1; line <= end; line++ {
+				lines[line] = true
+			}
+		}
+		return true
+	})
+
+	return lines
+}
+
+// removeLineDirectives removes the //line directives that refer to filename, which
+// are the ones added by the annotator.
+func removeLineDirectives(filename string, src []byte) []byte {
+	if !bytes.Contains(src, []byte(lineDirectivePrefix)) {
+		return src
+	}
+
+	base := filepath.Base(filename)
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(src, []byte("\n")) {
+		m := lineDirectiveRe.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+		if m != nil && filepath.Base(string(m[1])) == base {
+			continue
+		}
+		buf.Write(line)
+	}
+
+	return buf.Bytes()
+}
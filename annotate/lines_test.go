@@ -0,0 +1,191 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lineTestCode = `package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func Line() int {
+	_, _, line, _ := runtime.Caller(0)
+	return line
+}
+
+func Lines(n int) (lines []int) {
+	for i := 0; i < n; i++ {
+		_, _, line, _ := runtime.Caller(0)
+		lines = append(lines, line)
+	}
+	return
+}
+
+func main() {
+	fmt.Println(Line(), Lines(1)[0])
+}
+`
+
+func TestAnnotateSourceLineDirectives(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath:     "github.com/test/log",
+		LineDirectives: true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	annotated, err := annotator.AnnotateSource("test.go", []byte(lineTestCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	path, err := filepath.Abs("test.go")
+	if err != nil {
+		t.Fatalf("filepath.Abs failed: %v", err)
+	}
+
+	result := string(annotated)
+	for _, directive := range []string{"//line " + path + ":1\n", "//line " + path + ":8\n"} {
+		if !strings.Contains(result, directive) {
+			t.Errorf("Expected directive %q in annotated source:\n%s", directive, result)
+		}
+	}
+
+	stripped, err := annotator.StripSource("test.go", annotated)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+	if string(stripped) != lineTestCode {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+}
+
+func TestLineDirectivesRuntimeCaller(t *testing.T) {
+	output := runAnnotated(t, Options{
+		LineDirectives: true,
+		ShowReturn:     true,
+	}, lineTestCode)
+
+	// runtime.Caller is called on lines 9 and 15 of lineTestCode.
+	if output != "9 15" {
+		t.Errorf("Expected original lines 9 15, got %q", output)
+	}
+}
+
+func TestLineDirectivesWithoutImports(t *testing.T) {
+	files := map[string]string{
+		"main.go": "package main\n\nimport (\n\t\"fmt\"\n\t\"runtime\"\n)\n\nfunc line() int {\n\t_, _, line, _ := runtime.Caller(1)\n\treturn line\n}\n\nfunc main() {\n\tfmt.Println(Line())\n}\n",
+		// The log import is the first import of the file.
+		"d.go": "package main\n\nfunc Line() int {\n\treturn line()\n}\n",
+	}
+
+	for _, deferMode := range []bool{false, true} {
+		output := runAnnotatedFile(t, Options{LineDirectives: true, Defer: deferMode}, files, "d.go")
+		if output != "4" {
+			t.Errorf("Expected original line 4 with defer %v, got %q", deferMode, output)
+		}
+	}
+}
+
+// writeLogModule creates a temporary module that can import the log package of this
+// module. Tests using it are skipped in short mode and without a go command.
+func writeLogModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("Skipping build of an annotated program in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+
+	// Tests run in the package directory below the module root.
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatalf("Failed to resolve module root: %v", err)
+	}
+	mod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatalf("Failed to read go.sum: %v", err)
+	}
+
+	// The module shares the requirements of this module, so it builds offline.
+	files["go.mod"] = strings.Replace(string(mod), "module github.com/specmon/go-annotate", "module example.com/annotated", 1) +
+		"\nrequire github.com/specmon/go-annotate v0.0.0\n" +
+		"\nreplace github.com/specmon/go-annotate => " + root + "\n"
+	files["go.sum"] = string(sum)
+	t.Setenv("GOFLAGS", "-mod=readonly")
+	t.Setenv("GOPROXY", "off")
+
+	return writeModule(t, files)
+}
+
+// runAnnotated annotates a main package, runs it with the log package of this module
+// and returns its trimmed standard output. Logging is disabled.
+func runAnnotated(t *testing.T, config Options, code string) string {
+	t.Helper()
+
+	return runAnnotatedFile(t, config, map[string]string{"main.go": code}, "main.go")
+}
+
+// runAnnotatedFile annotates one file of a main package made of files and runs it
+// like runAnnotated.
+func runAnnotatedFile(t *testing.T, config Options, files map[string]string, name string) string {
+	t.Helper()
+
+	dir := writeLogModule(t, files)
+
+	config.ImportPath = "github.com/specmon/go-annotate/log"
+	annotator, err := New(&config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	source := filepath.Join(dir, name)
+	result, err := annotator.AnnotateFile(source)
+	if err != nil {
+		t.Fatalf("AnnotateFile failed: %v", err)
+	}
+	if err := os.WriteFile(source, result.Output, 0o644); err != nil {
+		t.Fatalf("Failed to write annotated source: %v", err)
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO_ANNOTATE_LOG_TARGET=")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("go run failed: %v\n%s", err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
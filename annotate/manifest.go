@@ -0,0 +1,89 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+// Function describes an instrumented function. The event name and the names of the
+// receiver, parameters and results are those of its log events.
+type Function struct {
+	// Name is the qualified event name, such as main_Conn_Read.
+	Name string `json:"name"`
+	// Package is the package name and PackagePath the import path, which is only known
+	// for files of loaded packages.
+	Package     string `json:"package"`
+	PackagePath string `json:"packagePath,omitempty"`
+	// File and Line locate the function in the original source.
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	Receiver     []Var  `json:"receiver,omitempty"`
+	Params       []Var  `json:"params"`
+	Results      []Var  `json:"results"`
+	NamedResults bool   `json:"namedResults"`
+	// Signature lists the static types of the arguments and results, if known.
+	Signature string `json:"signature,omitempty"`
+	// EventPattern is set for generic functions and methods of generic types, whose
+	// events are named after each instantiation. The type parameters stand for the
+	// type arguments, e.g. Stack[T]_Push for events such as Stack[int]_Push. The rules
+	// of these functions are triggered by the pattern instead of the name.
+	EventPattern string `json:"eventPattern,omitempty"`
+	// Stack reports whether panic events carry the stack.
+	Stack bool `json:"stack"`
+}
+
+// Var is a receiver, parameter or result of an instrumented function. Unnamed and
+// blank ones have the synthetic names they are logged with.
+type Var struct {
+	Name string `json:"name"`
+	// Type is the static type, which is only known in type-checked mode.
+	Type string `json:"type,omitempty"`
+}
+
+// ArgNames returns the names of the logged receiver and parameters.
+func (fn Function) ArgNames() []string {
+	return varNames(append(append([]Var(nil), fn.Receiver...), fn.Params...))
+}
+
+// ResultNames returns the names of the logged results.
+func (fn Function) ResultNames() []string {
+	return varNames(fn.Results)
+}
+
+// Manifest returns the functions instrumented so far in the order they were processed.
+func (a *Annotator) Manifest() []Function {
+	return a.functions
+}
+
+// newVars pairs names with their types, if known.
+func newVars(names, types []string) []Var {
+	vars := make([]Var, len(names))
+	for i, name := range names {
+		vars[i].Name = name
+		if i < len(types) {
+			vars[i].Type = types[i]
+		}
+	}
+	return vars
+}
+
+// varNames returns the names of vars.
+func varNames(vars []Var) []string {
+	names := make([]string, len(vars))
+	for i, v := range vars {
+		names[i] = v.Name
+	}
+	return names
+}
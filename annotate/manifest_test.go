@@ -0,0 +1,107 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Closures:   true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	code := `package svc
+
+type Conn struct{}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	return 0, nil
+}
+
+func Split(s string, _ int) (string, string) {
+	f := func() {}
+	f()
+	return s, s
+}
+`
+	if _, err := annotator.AnnotateSource("svc.go", []byte(code)); err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	expected := []Function{
+		{
+			Name:         "svc_Conn_Read",
+			Package:      "svc",
+			File:         "svc.go",
+			Line:         5,
+			Receiver:     []Var{{Name: "c"}},
+			Params:       []Var{{Name: "p"}},
+			Results:      []Var{{Name: "n"}, {Name: "err"}},
+			NamedResults: true,
+		},
+		{
+			Name:    "svc_Split_func1",
+			Package: "svc",
+			File:    "svc.go",
+			Line:    10,
+			Params:  []Var{},
+			Results: []Var{},
+		},
+		{
+			Name:    "svc_Split",
+			Package: "svc",
+			File:    "svc.go",
+			Line:    9,
+			Params:  []Var{{Name: "s"}, {Name: "__blank1"}},
+			Results: []Var{{Name: "res1"}, {Name: "res2"}},
+		},
+	}
+	if manifest := annotator.Manifest(); !reflect.DeepEqual(manifest, expected) {
+		t.Errorf("Unexpected manifest:\n%+v\nexpected:\n%+v", manifest, expected)
+	}
+}
+
+func TestManifestDeferNamedResults(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Defer:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	code := "package m\n\nfunc Line() int {\n\treturn 1\n}\n\nfunc Named() (n int) {\n\treturn 1\n}\n"
+	if _, err := annotator.AnnotateSource("m.go", []byte(code)); err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	// Defer mode names the results of Line, which are unnamed in the source.
+	manifest := annotator.Manifest()
+	if len(manifest) != 2 || manifest[0].NamedResults || !manifest[1].NamedResults {
+		t.Errorf("Unexpected named results in manifest %+v", manifest)
+	}
+	if len(manifest) == 2 && manifest[0].ResultNames()[0] != "__res0" {
+		t.Errorf("Expected logged result __res0, got %v", manifest[0].ResultNames())
+	}
+}
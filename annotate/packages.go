@@ -0,0 +1,100 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// loadMode is the package information needed to annotate package patterns.
+const loadMode = packages.NeedName | packages.NeedFiles
+
+// LoadPackages resolves package patterns relative to dir, honoring the build tags of
+// the options. With types enabled, the packages are type-checked for AddTypes and
+// AnnotatePackage.
+func (a *Annotator) LoadPackages(dir string, patterns ...string) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: loadMode,
+		Dir:  dir,
+	}
+	if a.config.Types {
+		cfg.Mode = typedLoadMode
+	}
+	if a.config.BuildTags != "" {
+		cfg.BuildFlags = []string{"-tags=" + a.config.BuildTags}
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	var errs []error
+	for _, pkg := range pkgs {
+		for _, pkgErr := range pkg.Errors {
+			errs = append(errs, pkgErr)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return pkgs, nil
+}
+
+// AnnotatePackage annotates every non-generated Go file of a loaded package, using its
+// type information if it was loaded with types enabled.
+func (a *Annotator) AnnotatePackage(pkg *packages.Package) ([]*Result, error) {
+	return a.AnnotatePackages(pkg)
+}
+
+// AnnotatePackages annotates the non-generated Go files of loaded packages concurrently,
+// like AnnotateFiles. The results follow the order of the packages and their files.
+func (a *Annotator) AnnotatePackages(pkgs ...*packages.Package) ([]*Result, error) {
+	var files []string
+	for _, pkg := range pkgs {
+		a.AddTypes(pkg)
+		files = append(files, pkg.GoFiles...)
+	}
+
+	return a.processFiles(files, func(fa *Annotator, file string) (*Result, error) {
+		generated, err := isGenerated(file)
+		if err != nil || generated {
+			return nil, err
+		}
+
+		return fa.processFile(file, os.ReadFile)
+	})
+}
+
+// isGenerated reports whether a file carries a "Code generated ... DO NOT EDIT." header.
+func isGenerated(file string) (bool, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse file %s: %w", file, err)
+	}
+
+	return ast.IsGenerated(f), nil
+}
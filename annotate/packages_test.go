@@ -0,0 +1,107 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a temporary module from a map of relative paths to file contents.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestAnnotatePackage(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":            "module example.com/svc\n\ngo 1.22\n",
+		"svc.go":            "package svc\n\nfunc Serve() {}\n",
+		"zz_generated.go":   "// Code generated by stringer. DO NOT EDIT.\n\npackage svc\n\nfunc Generated() {}\n",
+		"tagged.go":         "//go:build special\n\npackage svc\n\nfunc Tagged() {}\n",
+		"internal/db/db.go": "package db\n\nfunc Open() {}\n",
+	})
+
+	// annotateModule returns the relative paths of the instrumented files.
+	annotateModule := func(buildTags string) map[string]bool {
+		t.Helper()
+
+		annotator, err := New(&Options{
+			ImportPath: "github.com/test/log",
+			BuildTags:  buildTags,
+		})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+
+		pkgs, err := annotator.LoadPackages(dir, "./...")
+		if err != nil {
+			t.Fatalf("LoadPackages failed: %v", err)
+		}
+		if len(pkgs) != 2 {
+			t.Fatalf("Expected 2 packages, got %d", len(pkgs))
+		}
+
+		instrumented := make(map[string]bool)
+		for _, pkg := range pkgs {
+			results, err := annotator.AnnotatePackage(pkg)
+			if err != nil {
+				t.Fatalf("AnnotatePackage failed: %v", err)
+			}
+			for _, r := range results {
+				rel, err := filepath.Rel(dir, r.Filename)
+				if err != nil {
+					t.Fatalf("filepath.Rel failed: %v", err)
+				}
+				instrumented[filepath.ToSlash(rel)] = strings.Contains(string(r.Output), "LogEnter")
+			}
+		}
+		return instrumented
+	}
+
+	instrumented := annotateModule("")
+
+	if !instrumented["svc.go"] || !instrumented["internal/db/db.go"] {
+		t.Error("Package files were not instrumented")
+	}
+	if instrumented["zz_generated.go"] {
+		t.Error("Generated file was instrumented")
+	}
+	if instrumented["tagged.go"] {
+		t.Error("File excluded by build tags was instrumented")
+	}
+
+	instrumented = annotateModule("special")
+
+	if !instrumented["tagged.go"] {
+		t.Error("File selected by build tags was not instrumented")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"fmt"
+	"go/token"
+)
+
+// FuncError is an error in the instrumentation of a function. Func is empty for
+// function literals.
+type FuncError struct {
+	Pos  token.Position
+	Func string
+	Err  error
+}
+
+func (e *FuncError) Error() string {
+	if e.Func == "" {
+		return fmt.Sprintf("%s: function literal: %v", e.Pos, e.Err)
+	}
+	return fmt.Sprintf("%s: function %s: %v", e.Pos, e.Func, e.Err)
+}
+
+func (e *FuncError) Unwrap() error {
+	return e.Err
+}
+
+// Summary counts the functions handled by an annotator. Skipped functions were not
+// selected by the filters, the exported option or a skip directive. The errors of the
+// failed functions are kept in keep-going mode, where they do not stop the run. The
+// functions of failed files are not counted; the files are counted instead.
+type Summary struct {
+	Instrumented int
+	Skipped      int
+	Failed       int
+	FailedFiles  int
+	Errors       []error
+}
+
+func (s Summary) String() string {
+	str := fmt.Sprintf("%d functions instrumented, %d skipped, %d failed", s.Instrumented, s.Skipped, s.Failed)
+	if s.FailedFiles > 0 {
+		str += fmt.Sprintf(", %d files failed", s.FailedFiles)
+	}
+	return str
+}
+
+// add adds the counts and errors of another summary.
+func (s *Summary) add(other Summary) {
+	s.Instrumented += other.Instrumented
+	s.Skipped += other.Skipped
+	s.Failed += other.Failed
+	s.FailedFiles += other.FailedFiles
+	s.Errors = append(s.Errors, other.Errors...)
+}
+
+// Summary returns the functions handled so far.
+func (a *Annotator) Summary() Summary {
+	return a.summary
+}
+
+// funcError records the failure to instrument a function. In keep-going mode, the
+// error is kept for the summary and nil is returned, so that the function is skipped.
+func (a *Annotator) funcError(pos token.Pos, name string, err error) error {
+	a.summary.Failed++
+
+	err = &FuncError{Pos: a.fset.Position(pos), Func: name, Err: err}
+	if a.config.KeepGoing {
+		a.summary.Errors = append(a.summary.Errors, err)
+		return nil
+	}
+	return err
+}
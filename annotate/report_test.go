@@ -0,0 +1,148 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+const keepGoingTestCode = `package main
+
+func Add(a, b int) int {
+	return a + b
+}
+
+//annotate:unknown
+func Broken() {}
+
+//annotate:skip
+func Skipped() {}
+`
+
+func TestAnnotateSourceFuncError(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	_, err = annotator.AnnotateSource("test.go", []byte(keepGoingTestCode))
+	var funcErr *FuncError
+	if !errors.As(err, &funcErr) {
+		t.Fatalf("Expected a FuncError, got %v", err)
+	}
+	if funcErr.Func != "Broken" || funcErr.Pos.Filename != "test.go" || funcErr.Pos.Line != 8 {
+		t.Errorf("Error does not locate the function: %v", err)
+	}
+}
+
+func TestAnnotateSourceKeepGoing(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		KeepGoing:  true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := annotator.AnnotateSource("test.go", []byte(keepGoingTestCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if !strings.Contains(resultStr, `__log.LogEnter(__traceID, "Add"`) {
+		t.Error("Add is not instrumented")
+	}
+	if !strings.Contains(resultStr, "func Broken() {}") {
+		t.Error("Failing function is not left unchanged")
+	}
+
+	summary := annotator.Summary()
+	if summary.Instrumented != 1 || summary.Skipped != 1 || summary.Failed != 1 {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+	if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0].Error(), "test.go:8:1: function Broken") {
+		t.Errorf("Failure is not reported: %v", summary.Errors)
+	}
+}
+
+func TestAnnotateSourceTemplateError(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		KeepGoing:  true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// The instrumentation of Add does not parse.
+	annotator.enterTemplate = template.Must(template.New("enter").Parse(`{{if eq .Event "\"Add\""}}({{end}}`))
+
+	if _, err := annotator.AnnotateSource("test.go", []byte(keepGoingTestCode)); err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	summary := annotator.Summary()
+	if summary.Failed != 2 || len(summary.Errors) != 2 || !strings.Contains(summary.Errors[0].Error(), "invalid instrumentation") {
+		t.Errorf("Invalid instrumentation is not reported: %v", summary.Errors)
+	}
+}
+
+func TestSummaryString(t *testing.T) {
+	summary := Summary{Instrumented: 3, Skipped: 2, Failed: 1}
+	if s := summary.String(); s != "3 functions instrumented, 2 skipped, 1 failed" {
+		t.Errorf("Unexpected summary: %s", s)
+	}
+
+	summary.FailedFiles = 2
+	if s := summary.String(); s != "3 functions instrumented, 2 skipped, 1 failed, 2 files failed" {
+		t.Errorf("Unexpected summary: %s", s)
+	}
+}
+
+func TestSummaryFailedFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.go": {Data: []byte("package a\n\nfunc A() {}\n")},
+		// B is instrumented before Broken stops the file.
+		"b.go": {Data: []byte("package b\n\nfunc B() {}\n\n//annotate:unknown\nfunc Broken() {}\n")},
+	}
+
+	annotator, err := New(&Options{ImportPath: "github.com/test/log"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	results, err := annotator.AnnotateFS(fsys, "a.go", "b.go")
+	if err == nil || len(results) != 1 {
+		t.Fatalf("Expected one result and an error, got %v, %v", results, err)
+	}
+
+	summary := annotator.Summary()
+	if summary.Instrumented != 1 || summary.Failed != 0 || summary.FailedFiles != 1 {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+	if manifest := annotator.Manifest(); len(manifest) != 1 || manifest[0].Name != "a_A" {
+		t.Errorf("Manifest contains functions of the failed file: %v", manifest)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Result is the outcome of processing a source file.
+type Result struct {
+	// Filename is the name of the file as given to the annotator.
+	Filename string
+	// Source is the content of the file and Output its processed content.
+	Source []byte
+	Output []byte
+	// Functions are the functions instrumented in the file.
+	Functions []Function
+	// Diagnostics are the errors of the functions that were skipped in keep-going mode.
+	// They are of type *FuncError.
+	Diagnostics []error
+	// Cached reports whether the result was taken from the cache directory.
+	Cached bool
+
+	// base is the formatted source that the instrumentation was added to, if any, and
+	// generated marks the declarations and statements of the output that belong to
+	// the instrumentation, in source order.
+	base      []byte
+	generated []bool
+	// formatLength is the format length that the package of an instrumented file
+	// sets, see addInits.
+	formatLength int
+}
+
+// Changed reports whether processing changed the file.
+func (r *Result) Changed() bool {
+	return !bytes.Equal(r.Source, r.Output)
+}
+
+// AnnotateFile reads and annotates a Go source file, or strips it in strip mode. The
+// result is nil if the file is not selected by the file filters.
+func (a *Annotator) AnnotateFile(file string) (*Result, error) {
+	r, err := a.processFile(file, os.ReadFile)
+	if r != nil {
+		addInits([]*Result{r})
+	}
+	return r, err
+}
+
+// AnnotateFiles reads and annotates Go source files concurrently, or strips them in
+// strip mode. The results and the manifest follow the order of the files, whatever
+// the order they are processed in. Files not selected by the file filters have no
+// result.
+func (a *Annotator) AnnotateFiles(files ...string) ([]*Result, error) {
+	return a.processFiles(files, func(fa *Annotator, file string) (*Result, error) {
+		return fa.processFile(file, os.ReadFile)
+	})
+}
+
+// AnnotateFS reads and annotates the named files of fsys concurrently, or strips them in
+// strip mode. Names are slash-separated paths in fsys and name the files in the results.
+// Files not selected by the file filters have no result.
+func (a *Annotator) AnnotateFS(fsys fs.FS, names ...string) ([]*Result, error) {
+	readFile := func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}
+
+	return a.processFiles(names, func(fa *Annotator, name string) (*Result, error) {
+		return fa.processFile(name, readFile)
+	})
+}
+
+// processFiles runs process on each file with a pool of Jobs workers. Every file gets
+// its own annotator, whose manifest and summary are merged into a in the order of
+// the files once all of them are processed, so that the outcome does not depend on
+// scheduling, and the format length is set by the first file of each package. The
+// errors of all files are joined.
+func (a *Annotator) processFiles(files []string, process func(fa *Annotator, file string) (*Result, error)) ([]*Result, error) {
+	type outcome struct {
+		result    *Result
+		functions []Function
+		summary   Summary
+		err       error
+	}
+	outcomes := make([]outcome, len(files))
+
+	jobs := a.config.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	jobs = min(jobs, len(files))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fa := *a
+				fa.functions = nil
+				fa.summary = Summary{}
+				r, err := process(&fa, files[i])
+				outcomes[i] = outcome{r, fa.functions, fa.summary, err}
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var results []*Result
+	var errs []error
+	for _, o := range outcomes {
+		a.functions = append(a.functions, o.functions...)
+		a.summary.add(o.summary)
+		if o.err != nil {
+			errs = append(errs, o.err)
+			continue
+		}
+		if o.result != nil {
+			results = append(results, o.result)
+		}
+	}
+	addInits(results)
+
+	return results, errors.Join(errs...)
+}
+
+// addInits appends the init function that sets the format length of the logger to the
+// first instrumented file of each package among the results, where files with the same
+// package name in the same directory form a package. The limit applies to the whole
+// program, so it is set once per package rather than by every file.
+func addInits(results []*Result) {
+	packages := make(map[string]bool)
+	for _, r := range results {
+		if r.formatLength <= 0 {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), r.Filename, r.Output, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		pkg := filepath.Join(filepath.Dir(r.Filename), f.Name.Name)
+		if packages[pkg] {
+			continue
+		}
+		packages[pkg] = true
+
+		// The init function is appended as text, so that it cannot pick up file comments.
+		r.Output = fmt.Appendf(slices.Clip(r.Output), initTmpl, r.formatLength)
+	}
+}
+
+// processFile reads a file with readFile and processes it. With file options, the file
+// is processed with the options returned for it. If the file fails, its functions are
+// removed from the manifest and the summary and the file is counted as failed.
+func (a *Annotator) processFile(file string, readFile func(string) ([]byte, error)) (*Result, error) {
+	functions, before := len(a.functions), a.summary
+	r, err := a.process(file, readFile)
+	if err != nil {
+		a.functions = a.functions[:functions]
+		a.summary = before
+		a.summary.FailedFiles++
+	}
+
+	return r, err
+}
+
+// process reads and processes a file for processFile.
+func (a *Annotator) process(file string, readFile func(string) ([]byte, error)) (*Result, error) {
+	if a.config.FileOptions != nil {
+		fa, err := a.forFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := fa.processFile(file, readFile)
+		a.functions = append(a.functions, fa.functions...)
+		a.summary.add(fa.summary)
+		return r, err
+	}
+
+	if !a.filter.file(file) {
+		return nil, nil
+	}
+
+	orig, err := readFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+
+	r := &Result{Filename: file, Source: orig}
+	var key string
+	if a.config.CacheDir != "" {
+		key, err = a.cacheKey(file, orig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cache file %s: %w", file, err)
+		}
+		if a.loadCached(key, r) {
+			return r, nil
+		}
+	}
+
+	functions, before := len(a.functions), a.summary
+	if a.config.Strip {
+		r.Output, err = a.StripSource(file, orig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to strip file %s: %w", file, err)
+		}
+	} else {
+		if err := a.annotateSource(r); err != nil {
+			return nil, fmt.Errorf("failed to annotate file %s: %w", file, err)
+		}
+	}
+	r.Functions = slices.Clone(a.functions[functions:])
+	r.Diagnostics = slices.Clone(a.summary.Errors[len(before.Errors):])
+
+	if key != "" {
+		summary := Summary{
+			Instrumented: a.summary.Instrumented - before.Instrumented,
+			Skipped:      a.summary.Skipped - before.Skipped,
+			Failed:       a.summary.Failed - before.Failed,
+		}
+		if err := a.storeCached(key, r, summary); err != nil {
+			return nil, fmt.Errorf("failed to cache file %s: %w", file, err)
+		}
+	}
+
+	return r, nil
+}
+
+// forFile returns an annotator for a single file that uses the options returned for
+// the file. It shares the type information with a but collects its own manifest.
+func (a *Annotator) forFile(file string) (*Annotator, error) {
+	config, err := a.config.FileOptions(file)
+	if err != nil {
+		return nil, err
+	}
+	opts := *config
+	opts.FileOptions = nil
+
+	filter, err := newFilter(&opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	fa := *a
+	fa.config = &opts
+	fa.filter = filter
+	fa.functions = nil
+	fa.summary = Summary{}
+	return &fa, nil
+}
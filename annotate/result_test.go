@@ -0,0 +1,194 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAnnotateFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cmd/app/main.go":     {Data: []byte("package main\n\nfunc main() {}\n")},
+		"cmd/app/main_gen.go": {Data: []byte("package main\n\nfunc generated() {}\n")},
+		"internal/bad.go":     {Data: []byte("package internal\n\nfunc Bad(\n")},
+	}
+
+	annotator, err := New(&Options{
+		ImportPath:   "github.com/test/log",
+		ExcludeFiles: []string{"*_gen.go"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	results, err := annotator.AnnotateFS(fsys, "cmd/app/main.go", "cmd/app/main_gen.go")
+	if err != nil {
+		t.Fatalf("AnnotateFS failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Filename != "cmd/app/main.go" || !strings.Contains(string(results[0].Output), "LogEnter") {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	if _, err := annotator.AnnotateFS(fsys, "internal/bad.go"); err == nil || !strings.Contains(err.Error(), "internal/bad.go") {
+		t.Errorf("Expected error naming internal/bad.go, got %v", err)
+	}
+}
+
+func TestAnnotateFSFormatLength(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/doc.go":    {Data: []byte("package a\n")},
+		"a/x.go":      {Data: []byte("package a\n\nfunc X() {}\n")},
+		"a/y.go":      {Data: []byte("package a\n\nfunc Y() {}\n")},
+		"a/x_test.go": {Data: []byte("package a_test\n\nfunc T() {}\n")},
+		"b/b.go":      {Data: []byte("package b\n\nfunc B() {}\n")},
+	}
+	names := []string{"a/doc.go", "a/x.go", "a/y.go", "a/x_test.go", "b/b.go"}
+
+	annotator, err := New(&Options{ImportPath: "github.com/test/log", FormatLength: 64, Jobs: 4})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	results, err := annotator.AnnotateFS(fsys, names...)
+	if err != nil {
+		t.Fatalf("AnnotateFS failed: %v", err)
+	}
+
+	want := map[string]int{"a/doc.go": 0, "a/x.go": 1, "a/y.go": 0, "a/x_test.go": 1, "b/b.go": 1}
+	for _, r := range results {
+		if n := strings.Count(string(r.Output), "SetFormatLength(64)"); n != want[r.Filename] {
+			t.Errorf("%s sets the format length %d times, want %d:\n%s", r.Filename, n, want[r.Filename], r.Output)
+		}
+	}
+
+	// Annotating the output again moves no init function.
+	annotated := fstest.MapFS{}
+	for _, r := range results {
+		annotated[r.Filename] = &fstest.MapFile{Data: r.Output}
+	}
+	again, err := annotator.AnnotateFS(annotated, names...)
+	if err != nil {
+		t.Fatalf("AnnotateFS failed: %v", err)
+	}
+	for i, r := range again {
+		if r.Changed() {
+			t.Errorf("%s changed when annotated again:\n%s\nwant:\n%s", r.Filename, r.Output, results[i].Output)
+		}
+	}
+}
+
+func TestAnnotateFSDiagnosticsAndFileOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/a.go": {Data: []byte("package a\n\nfunc A() {}\n\n//annotate:redact missing\nfunc Bad() {}\n")},
+		"b/b.go": {Data: []byte("package b\n\nfunc B() {}\n\nfunc b() {}\n")},
+	}
+
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		KeepGoing:  true,
+		FileOptions: func(file string) (*Options, error) {
+			return &Options{
+				ImportPath:   "github.com/test/log",
+				KeepGoing:    true,
+				ExportedOnly: strings.HasPrefix(file, "b/"),
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	results, err := annotator.AnnotateFS(fsys, "a/a.go", "b/b.go")
+	if err != nil {
+		t.Fatalf("AnnotateFS failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	a, b := results[0], results[1]
+	if len(a.Functions) != 1 || a.Functions[0].Name != "a_A" || len(a.Diagnostics) != 1 {
+		t.Errorf("Unexpected functions %v or diagnostics %v of a/a.go", a.Functions, a.Diagnostics)
+	}
+	var funcErr *FuncError
+	if len(a.Diagnostics) == 1 && (!errors.As(a.Diagnostics[0], &funcErr) || funcErr.Func != "Bad") {
+		t.Errorf("Diagnostic does not name the function: %v", a.Diagnostics[0])
+	}
+	if len(b.Functions) != 1 || b.Functions[0].Name != "b_B" || len(b.Diagnostics) != 0 {
+		t.Errorf("Options for b/b.go not applied: %v, %v", b.Functions, b.Diagnostics)
+	}
+
+	summary := annotator.Summary()
+	if summary.Instrumented != 2 || summary.Skipped != 1 || summary.Failed != 1 || len(annotator.Manifest()) != 2 {
+		t.Errorf("Unexpected summary %v or manifest %v", summary, annotator.Manifest())
+	}
+}
+
+func TestAnnotateFSJobs(t *testing.T) {
+	fsys := fstest.MapFS{}
+	var names []string
+	for i := range 40 {
+		name := fmt.Sprintf("p%d/f.go", i)
+		fsys[name] = &fstest.MapFile{Data: []byte(fmt.Sprintf("package p%d\n\nfunc F(a int) int { return a }\n\n//annotate:redact missing\nfunc G() {}\n\nfunc H(s string) (string, error) { return s, nil }\n", i))}
+		names = append(names, name)
+	}
+
+	run := func(jobs int) ([]*Result, *Annotator) {
+		annotator, err := New(&Options{
+			ImportPath: "github.com/test/log",
+			ShowReturn: true,
+			KeepGoing:  true,
+			Jobs:       jobs,
+		})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		results, err := annotator.AnnotateFS(fsys, names...)
+		if err != nil {
+			t.Fatalf("AnnotateFS failed: %v", err)
+		}
+		return results, annotator
+	}
+
+	sequential, want := run(1)
+	parallel, got := run(8)
+
+	if len(parallel) != len(sequential) {
+		t.Fatalf("Expected %d results, got %d", len(sequential), len(parallel))
+	}
+	for i, r := range parallel {
+		if r.Filename != names[i] || !bytes.Equal(r.Output, sequential[i].Output) {
+			t.Errorf("Result %d for %s differs from the sequential run", i, r.Filename)
+		}
+	}
+	if !reflect.DeepEqual(got.Manifest(), want.Manifest()) {
+		t.Errorf("Manifest differs from the sequential run")
+	}
+	if GenerateTheory(got.Manifest()) != GenerateTheory(want.Manifest()) {
+		t.Errorf("Theory differs from the sequential run")
+	}
+	if got.Summary().String() != want.Summary().String() || got.Summary().Failed != len(names) {
+		t.Errorf("Unexpected summary %v, want %v", got.Summary(), want.Summary())
+	}
+}
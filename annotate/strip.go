@@ -0,0 +1,836 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const (
+	traceIDName = "__traceID"
+	panicName   = "__panic"
+	startName   = "__traceStart"
+)
+
+// errModified is returned for functions whose instrumentation no longer has the generated shape.
+var errModified = errors.New("instrumentation was modified after annotation")
+
+// StripSource removes go-annotate instrumentation from Go source code, restoring the
+// original function bodies and dropping the log import. Functions whose instrumentation
+// was edited by hand are reported and the source is left unchanged.
+func (a *Annotator) StripSource(filename string, orig []byte) ([]byte, error) {
+	orig = removeLineDirectives(filename, orig)
+
+	f, err := parser.ParseFile(a.fset, filename, orig, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	edits, err := a.stripEdits(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// The original bodies keep the indentation of the closures they were moved into.
+	src, err := format.Source(applyEdits(orig, edits))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format stripped source: %w", err)
+	}
+
+	f, err = parser.ParseFile(a.fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	removeLogImport(a.fset, f)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, a.fset, f); err != nil {
+		return nil, fmt.Errorf("format.Node: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// edit replaces the source bytes between two offsets.
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+// applyEdits applies non-overlapping edits to src.
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		buf.Write(src[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes()
+}
+
+// stripEdits returns the edits that remove the instrumentation from every annotated
+// function and function literal of a parsed file. Original bodies that refer to the
+// names of the instrumentation are taken as modified.
+func (a *Annotator) stripEdits(f *ast.File) ([]edit, error) {
+	sf := &strippedFile{comments: f.Comments, markers: endMarkers(f)}
+
+	var edits []edit
+	var errs []error
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncDecl:
+			if isInitDecl(node) {
+				edits = append(edits, a.deletion(node.Pos(), node.End()))
+				return false
+			}
+
+			if node.Body == nil || sf.markers[node.Body] == nil && !sf.usesInstrumentation(node.Body) {
+				return true
+			}
+
+			funcEdits, err := a.stripFunction(sf, a.extractFunctionInfo(node), node.Type, node.Body)
+			if err != nil {
+				errs = append(errs, &FuncError{Pos: a.fset.Position(node.Pos()), Func: funcName(node), Err: err})
+				return true
+			}
+			edits = append(edits, funcEdits...)
+			edits = append(edits, a.stripNames(node.Recv, node.Type)...)
+		case *ast.FuncLit:
+			// The closures generated around the original body and for LogLeave are
+			// part of the enclosing instrumentation and are matched there.
+			if !sf.isInstrumented(node.Body) {
+				return true
+			}
+
+			funcEdits, err := a.stripFunction(sf, newFunctionInfo("", nil, node.Type), node.Type, node.Body)
+			if err != nil {
+				errs = append(errs, &FuncError{Pos: a.fset.Position(node.Pos()), Err: err})
+				return true
+			}
+			edits = append(edits, funcEdits...)
+			edits = append(edits, a.stripNames(nil, node.Type)...)
+		}
+		return true
+	})
+
+	return edits, errors.Join(errs...)
+}
+
+// strippedFile is a parsed file whose instrumentation is stripped.
+type strippedFile struct {
+	// comments are the comments of the file, which tell empty bodies from bodies with
+	// only comments.
+	comments []*ast.CommentGroup
+	// markers are the end markers of the instrumented function bodies.
+	markers map[*ast.BlockStmt]*ast.Comment
+}
+
+// endMarkers returns the end markers of the function bodies of a file, which are only
+// matched between the statements of a body.
+func endMarkers(f *ast.File) map[*ast.BlockStmt]*ast.Comment {
+	var candidates []*ast.Comment
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if c.Text == endMarker || c.Text == endMarker+" "+oneline {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	markers := make(map[*ast.BlockStmt]*ast.Comment)
+	ast.Inspect(f, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch node := n.(type) {
+		case *ast.FuncDecl:
+			body = node.Body
+		case *ast.FuncLit:
+			body = node.Body
+		}
+		if body == nil {
+			return true
+		}
+
+		for _, c := range candidates {
+			if c.Pos() < body.Lbrace || body.Rbrace < c.End() {
+				continue
+			}
+			inStmt := false
+			for _, stmt := range body.List {
+				if stmt.Pos() <= c.Pos() && c.End() <= stmt.End() {
+					inStmt = true
+					break
+				}
+			}
+			if !inStmt {
+				markers[body] = c
+				break
+			}
+		}
+		return true
+	})
+
+	return markers
+}
+
+// stripFunction returns the edits that restore the original body of an annotated function.
+func (a *Annotator) stripFunction(sf *strippedFile, info *functionInfo, typ *ast.FuncType, block *ast.BlockStmt) ([]edit, error) {
+	args := append(info.ReceiverNames, info.ArgNames...)
+
+	list := block.List
+	if len(list) < 3 || !isTraceIDStmt(list[0]) {
+		if marker := sf.markers[block]; marker != nil {
+			return a.stripCustom(sf, info, typ, block, marker)
+		}
+		return nil, errModified
+	}
+
+	name, ok := matchEnterStmt(list[1], args)
+	if !ok {
+		return nil, errModified
+	}
+
+	rest := list[2:]
+	timed := isStartStmt(rest[0])
+	if timed {
+		rest = rest[1:]
+	}
+
+	if lit, ok := matchLegacyBody(rest, name, args, typ.Results, info, timed); ok {
+		return a.unwrapBody(sf, block, lit)
+	}
+
+	wrapped := len(info.RetNames) > 0 && !info.HasNamedReturn
+	if wrapped {
+		if len(rest) == 0 || !matchResultDecl(rest[0], typ.Results, info.RetNames) {
+			return nil, errModified
+		}
+		rest = rest[1:]
+	}
+
+	if len(rest) == 0 || !matchLeaveStmt(rest[0], name, args, info.RetNames, timed) {
+		return nil, errModified
+	}
+
+	if lit, ok := matchWrappedBody(rest[1:], typ.Results, info); ok {
+		return a.unwrapBody(sf, block, lit)
+	} else if wrapped {
+		return nil, errModified
+	}
+
+	// The body was left in place, as for functions without results and in defer mode.
+	return a.restoreBody(sf, block, rest[0].End(), rest[1:])
+}
+
+// stripCustom returns the edits that restore the original body of a function annotated
+// with custom templates, whose instrumentation ends with the marker.
+func (a *Annotator) stripCustom(sf *strippedFile, info *functionInfo, typ *ast.FuncType, block *ast.BlockStmt, marker *ast.Comment) ([]edit, error) {
+	var rest []ast.Stmt
+	for i, stmt := range block.List {
+		if stmt.Pos() > marker.Pos() {
+			rest = block.List[i:]
+			break
+		}
+	}
+
+	if lit, ok := matchWrappedBody(rest, typ.Results, info); ok {
+		return a.unwrapBody(sf, block, lit)
+	} else if len(info.RetNames) > 0 && !info.HasNamedReturn {
+		return nil, errModified
+	}
+
+	return a.restoreBody(sf, block, marker.End(), rest)
+}
+
+// restoreBody returns the edits that remove the instrumentation up to end from a body
+// that was left in place, as for functions without results and in defer mode.
+func (a *Annotator) restoreBody(sf *strippedFile, block *ast.BlockStmt, end token.Pos, body []ast.Stmt) ([]edit, error) {
+	if sf.stmtsUseInstrumentation(body) {
+		return nil, errModified
+	}
+
+	// Restore empty bodies as {}, and bodies written on one line as such.
+	if len(body) == 0 && !hasComment(sf.comments, end, block.Rbrace) {
+		end = block.Rbrace
+	}
+	if len(body) > 0 && sf.isOneline(block, body[0].Pos()) {
+		return a.joinBody(block, body), nil
+	}
+
+	return []edit{a.deletion(block.Lbrace+1, end)}, nil
+}
+
+// isOneline reports whether a body is marked as written on one line and the original
+// body from start has no comments, which could not stay on one line. The markers of
+// instrumented function literals in the body are removed with their instrumentation.
+func (sf *strippedFile) isOneline(block *ast.BlockStmt, start token.Pos) bool {
+	if marker := sf.markers[block]; marker == nil || marker.Text != endMarker+" "+oneline {
+		return false
+	}
+
+	for _, group := range sf.comments {
+		for _, c := range group.List {
+			if start <= c.Pos() && c.End() <= block.Rbrace && !strings.HasPrefix(c.Text, endMarker) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// joinBody returns the edits that remove the instrumentation of a body left in place
+// and join its statements on one line, as gofmt prints short bodies written that way.
+func (a *Annotator) joinBody(block *ast.BlockStmt, body []ast.Stmt) []edit {
+	edits := []edit{a.replacement(block.Lbrace+1, body[0].Pos(), " ")}
+	for i := 1; i < len(body); i++ {
+		edits = append(edits, a.replacement(body[i-1].End(), body[i].Pos(), "; "))
+	}
+
+	return append(edits, a.replacement(body[len(body)-1].End(), block.Rbrace, " "))
+}
+
+// unwrapBody returns the edits that replace the body of an annotated function with the
+// original body, which was moved into a closure.
+func (a *Annotator) unwrapBody(sf *strippedFile, block *ast.BlockStmt, lit *ast.FuncLit) ([]edit, error) {
+	if sf.stmtsUseInstrumentation(lit.Body.List) {
+		return nil, errModified
+	}
+
+	return []edit{
+		a.deletion(block.Lbrace+1, lit.Body.Lbrace+1),
+		a.deletion(lit.Body.Rbrace, block.Rbrace),
+	}, nil
+}
+
+// hasComment reports whether a comment lies between two positions.
+func hasComment(comments []*ast.CommentGroup, start, end token.Pos) bool {
+	for _, c := range comments {
+		if start <= c.Pos() && c.End() <= end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWrappedBody matches the call of the closure that runs the original body of a
+// function with results, followed by the return of the results.
+func matchWrappedBody(stmts []ast.Stmt, results *ast.FieldList, info *functionInfo) (*ast.FuncLit, bool) {
+	if len(info.RetNames) == 0 || len(stmts) != 2 {
+		return nil, false
+	}
+
+	lit, ok := matchFunctionCall(stmts[0], results, info)
+	if !ok || !matchReturnStmt(stmts[1], info) {
+		return nil, false
+	}
+
+	return lit, true
+}
+
+// matchLegacyBody matches the original body of a function with results as annotated
+// by releases before panics were logged, where the closure runs ahead of the deferred
+// LogLeave and its call declares unnamed results.
+func matchLegacyBody(stmts []ast.Stmt, name string, args []string, results *ast.FieldList, info *functionInfo, timed bool) (*ast.FuncLit, bool) {
+	if len(info.RetNames) == 0 || len(stmts) != 3 {
+		return nil, false
+	}
+
+	lit, ok := matchFunctionCall(stmts[0], results, info)
+	if !ok || !matchLeaveStmt(stmts[1], name, args, info.RetNames, timed) || !matchReturnStmt(stmts[2], info) {
+		return nil, false
+	}
+
+	return lit, true
+}
+
+// stripNames returns the edits that restore the unnamed and blank parameters, receivers
+// and results named by nameSignature.
+func (a *Annotator) stripNames(recv *ast.FieldList, typ *ast.FuncType) []edit {
+	var edits []edit
+	for _, list := range []*ast.FieldList{recv, typ.Params, typ.Results} {
+		if list == nil {
+			continue
+		}
+
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				switch {
+				case len(field.Names) == 1 && list == typ.Results && len(list.List) == 1 && isSyntheticName(name.Name, namedResultPrefix):
+					// A single unnamed result is written without parentheses.
+					edits = append(edits,
+						a.deletion(list.Opening, field.Type.Pos()),
+						a.deletion(field.Type.End(), list.Closing+1))
+				case len(field.Names) == 1 && (name.Name == recvName || isSyntheticName(name.Name, argPrefix) || isSyntheticName(name.Name, namedResultPrefix)):
+					edits = append(edits, a.deletion(name.Pos(), field.Type.Pos()))
+				case name.Name == blankRecvName || isSyntheticName(name.Name, blankPrefix) || isSyntheticName(name.Name, blankResultPrefix):
+					e := a.deletion(name.Pos(), name.End())
+					e.text = "_"
+					edits = append(edits, e)
+				}
+			}
+		}
+	}
+
+	return edits
+}
+
+// isSyntheticName reports whether name is prefix followed by a position.
+func isSyntheticName(name, prefix string) bool {
+	index, ok := strings.CutPrefix(name, prefix)
+	if !ok || index == "" {
+		return false
+	}
+
+	for _, c := range index {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// deletion returns an edit that removes the source between two positions.
+func (a *Annotator) deletion(start, end token.Pos) edit {
+	file := a.fset.File(start)
+	return edit{start: file.Offset(start), end: file.Offset(end)}
+}
+
+// replacement returns an edit that replaces the source between two positions with text.
+func (a *Annotator) replacement(start, end token.Pos, text string) edit {
+	e := a.deletion(start, end)
+	e.text = text
+	return e
+}
+
+// isInitDecl matches the generated init function that sets the format length.
+func isInitDecl(decl *ast.FuncDecl) bool {
+	if decl.Recv != nil || decl.Name.Name != "init" || decl.Body == nil || len(decl.Body.List) != 1 {
+		return false
+	}
+
+	expr, ok := decl.Body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+
+	call, ok := matchLogCall(expr.X, "SetFormatLength")
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	return ok && lit.Kind == token.INT
+}
+
+// isInstrumented reports whether a function body starts with the generated trace ID or
+// carries an end marker.
+func (sf *strippedFile) isInstrumented(body *ast.BlockStmt) bool {
+	return len(body.List) > 0 && isTraceIDStmt(body.List[0]) || sf.markers[body] != nil
+}
+
+// isTraceIDStmt matches "__traceID := __log.ID()".
+func isTraceIDStmt(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return false
+	}
+
+	call, ok := matchLogCall(assign.Rhs[0], "ID")
+	return ok && isIdent(assign.Lhs[0], traceIDName) && len(call.Args) == 0
+}
+
+// matchEnterStmt matches the LogEnter call and returns the logged function name.
+func matchEnterStmt(stmt ast.Stmt, args []string) (string, bool) {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return "", false
+	}
+
+	call, ok := matchLogCall(expr.X, "LogEnter")
+	if !ok || len(call.Args) != 3 || !isIdent(call.Args[0], traceIDName) || !matchValueList(call.Args[2], args) {
+		return "", false
+	}
+
+	return matchName(call.Args[1])
+}
+
+// isStartStmt matches "__traceStart := __log.Now()".
+func isStartStmt(stmt ast.Stmt) bool {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return false
+	}
+
+	call, ok := matchLogCall(assign.Rhs[0], "Now")
+	return ok && isIdent(assign.Lhs[0], startName) && len(call.Args) == 0
+}
+
+// matchLeaveStmt matches the deferred closure that calls LogPanic and LogLeave, or
+// LogLeaveTimed for timed functions, for the named function. Releases before panics
+// were logged only called LogLeave.
+func matchLeaveStmt(stmt ast.Stmt, name string, args []string, results []string, timed bool) bool {
+	deferStmt, ok := stmt.(*ast.DeferStmt)
+	if !ok || len(deferStmt.Call.Args) != 0 {
+		return false
+	}
+
+	lit, ok := deferStmt.Call.Fun.(*ast.FuncLit)
+	if !ok || lit.Type.Params.NumFields() != 0 || lit.Type.Results != nil {
+		return false
+	}
+
+	stmts := lit.Body.List
+	if len(stmts) == 2 {
+		if !matchPanicStmt(stmts[0], name, args) {
+			return false
+		}
+		stmts = stmts[1:]
+	}
+	if len(stmts) != 1 {
+		return false
+	}
+
+	expr, ok := stmts[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+
+	var call *ast.CallExpr
+	if timed {
+		call, ok = matchLogCall(expr.X, "LogLeaveTimed")
+		ok = ok && len(call.Args) == 5 && isIdent(call.Args[4], startName)
+	} else {
+		call, ok = matchLogCall(expr.X, "LogLeave")
+		ok = ok && len(call.Args) == 4
+	}
+	if !ok || !isIdent(call.Args[0], traceIDName) {
+		return false
+	}
+
+	leaveName, ok := matchName(call.Args[1])
+	return ok && leaveName == name && matchValueList(call.Args[2], args) && matchValueList(call.Args[3], results)
+}
+
+// matchPanicStmt matches the recovery of an in-flight panic, which is logged with
+// LogPanic and raised again.
+func matchPanicStmt(stmt ast.Stmt, name string, args []string) bool {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok || ifStmt.Else != nil || len(ifStmt.Body.List) != 2 {
+		return false
+	}
+
+	// if __panic := recover(); __panic != nil
+	init, ok := ifStmt.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 || !isIdent(init.Lhs[0], panicName) {
+		return false
+	}
+	if recoverCall, ok := init.Rhs[0].(*ast.CallExpr); !ok || !isIdent(recoverCall.Fun, "recover") || len(recoverCall.Args) != 0 {
+		return false
+	}
+	if cond, ok := ifStmt.Cond.(*ast.BinaryExpr); !ok || cond.Op != token.NEQ || !isIdent(cond.X, panicName) || !isIdent(cond.Y, "nil") {
+		return false
+	}
+
+	expr, ok := ifStmt.Body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+
+	call, ok := matchLogCall(expr.X, "LogPanic")
+	if !ok || len(call.Args) != 5 || !isIdent(call.Args[0], traceIDName) || !isIdent(call.Args[3], panicName) {
+		return false
+	}
+	if !isIdent(call.Args[4], "true") && !isIdent(call.Args[4], "false") {
+		return false
+	}
+
+	panicEvent, ok := matchName(call.Args[1])
+	if !ok || panicEvent != name || !matchValueList(call.Args[2], args) {
+		return false
+	}
+
+	// panic(__panic)
+	expr, ok = ifStmt.Body.List[1].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	repanic, ok := expr.X.(*ast.CallExpr)
+	return ok && isIdent(repanic.Fun, "panic") && len(repanic.Args) == 1 && isIdent(repanic.Args[0], panicName)
+}
+
+// matchResultDecl matches the declaration of the unnamed results.
+func matchResultDecl(stmt ast.Stmt, results *ast.FieldList, names []string) bool {
+	declStmt, ok := stmt.(*ast.DeclStmt)
+	if !ok {
+		return false
+	}
+
+	decl, ok := declStmt.Decl.(*ast.GenDecl)
+	if !ok || decl.Tok != token.VAR || len(decl.Specs) != len(results.List) || len(names) != len(results.List) {
+		return false
+	}
+
+	for i, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok || len(valueSpec.Names) != 1 || valueSpec.Names[0].Name != names[i] || valueSpec.Values != nil {
+			return false
+		}
+		if valueSpec.Type == nil || types.ExprString(valueSpec.Type) != types.ExprString(results.List[i].Type) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchFunctionCall matches the assignment of the immediately invoked closure holding the
+// original body and returns the closure.
+func matchFunctionCall(stmt ast.Stmt, results *ast.FieldList, info *functionInfo) (*ast.FuncLit, bool) {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) != len(info.RetNames) {
+		return nil, false
+	}
+
+	// Releases before panics were logged declared unnamed results with the call.
+	if assign.Tok != token.ASSIGN && (assign.Tok != token.DEFINE || info.HasNamedReturn) {
+		return nil, false
+	}
+
+	for i, lhs := range assign.Lhs {
+		if !isIdent(lhs, info.RetNames[i]) {
+			return nil, false
+		}
+	}
+
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok || len(call.Args) != 0 {
+		return nil, false
+	}
+
+	lit, ok := call.Fun.(*ast.FuncLit)
+	if !ok || lit.Type.Params.NumFields() != 0 || !sameFields(lit.Type.Results, results) {
+		return nil, false
+	}
+
+	return lit, true
+}
+
+// matchReturnStmt matches the return statement that forwards the closure results.
+func matchReturnStmt(stmt ast.Stmt, info *functionInfo) bool {
+	ret, ok := stmt.(*ast.ReturnStmt)
+	if !ok {
+		return false
+	}
+
+	if info.HasNamedReturn {
+		return len(ret.Results) == 0
+	}
+
+	if len(ret.Results) != len(info.RetNames) {
+		return false
+	}
+	for i, result := range ret.Results {
+		if !isIdent(result, info.RetNames[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLogCall matches a call of the given function of the log package.
+func matchLogCall(expr ast.Expr, fn string) (*ast.CallExpr, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isIdent(sel.X, importName) || sel.Sel.Name != fn {
+		return nil, false
+	}
+
+	return call, true
+}
+
+// matchValueList matches a "[]any{...}" literal listing exactly the given identifiers,
+// any of which may be redacted or converted to another type.
+func matchValueList(expr ast.Expr, names []string) bool {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || len(lit.Elts) != len(names) {
+		return false
+	}
+
+	arr, ok := lit.Type.(*ast.ArrayType)
+	if !ok || arr.Len != nil || !isIdent(arr.Elt, "any") {
+		return false
+	}
+
+	for i, elt := range lit.Elts {
+		if !isIdent(elt, names[i]) && types.ExprString(elt) != redactedValue && !isConversion(elt, names[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchName matches the event name expression generated by eventName: a string literal,
+// or a concatenation that adds type arguments, and returns it in printed form.
+func matchName(expr ast.Expr) (string, bool) {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return "", false
+		}
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	return types.ExprString(expr), true
+}
+
+// isConversion matches a conversion of the identifier name to a basic, slice or array type.
+func isConversion(expr ast.Expr, name string) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 || call.Ellipsis.IsValid() || !isIdent(call.Args[0], name) {
+		return false
+	}
+
+	switch call.Fun.(type) {
+	case *ast.Ident, *ast.ArrayType:
+		return true
+	}
+	return false
+}
+
+// isIdent reports whether expr is the identifier name.
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// sameFields reports whether two field lists declare the same names and types.
+func sameFields(x, y *ast.FieldList) bool {
+	if x.NumFields() != y.NumFields() || len(x.List) != len(y.List) {
+		return false
+	}
+
+	for i, field := range x.List {
+		other := y.List[i]
+		if len(field.Names) != len(other.Names) || types.ExprString(field.Type) != types.ExprString(other.Type) {
+			return false
+		}
+		for j, name := range field.Names {
+			if name.Name != other.Names[j].Name {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// usesInstrumentation reports whether node refers to the log import or the generated
+// trace variables.
+// Instrumented function literals are matched on their own and are not looked into.
+func (sf *strippedFile) usesInstrumentation(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && sf.isInstrumented(lit.Body) {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok && (ident.Name == importName || ident.Name == traceIDName || ident.Name == startName) {
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
+
+// stmtsUseInstrumentation reports whether any of the statements of an original body
+// refers to the instrumentation.
+func (sf *strippedFile) stmtsUseInstrumentation(stmts []ast.Stmt) bool {
+	for _, stmt := range stmts {
+		if sf.usesInstrumentation(stmt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeLogImport deletes the named log import once nothing in the file refers to it anymore.
+func removeLogImport(fset *token.FileSet, f *ast.File) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			imp, ok := spec.(*ast.ImportSpec)
+			if !ok || imp.Name == nil || imp.Name.Name != importName {
+				continue
+			}
+
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || astutil.UsesImport(f, path) {
+				continue
+			}
+
+			astutil.DeleteNamedImport(fset, f, importName, path)
+
+			// AddNamedImport parenthesizes a single import, undo that as well.
+			if len(gen.Specs) == 1 {
+				gen.Lparen = token.NoPos
+			}
+			return
+		}
+	}
+}
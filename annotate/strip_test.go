@@ -0,0 +1,445 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"go/format"
+	"strings"
+	"testing"
+)
+
+const stripTestCode = `package main
+
+import "fmt"
+
+type Counter struct {
+	n int
+}
+
+// Inc increments the counter.
+func (c *Counter) Inc(delta int) {
+	c.n += delta // may overflow
+}
+
+func Add(a, b int) int {
+	// The sum.
+	return a + b
+}
+
+func Divide(a, b int) (q int, err error) {
+	if b == 0 {
+		/* The divisor is checked
+		   before dividing. */
+		return 0, fmt.Errorf("division by zero")
+	}
+	q = a / b
+	return
+}
+
+func Noop() {
+	// Nothing to do.
+}
+
+func main() {
+	fmt.Println(Add(1, 2))
+}
+`
+
+func TestStripSourceRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config Options
+	}{
+		{name: "default"},
+		{name: "timing", config: Options{Timing: true}},
+		{name: "stack", config: Options{Stack: true, ShowPackage: true}},
+		{name: "format length", config: Options{FormatLength: 1024}},
+		{name: "defer", config: Options{Defer: true, Timing: true}},
+	}
+
+	expected, err := format.Source([]byte(stripTestCode))
+	if err != nil {
+		t.Fatalf("format.Source failed: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			config.ImportPath = "github.com/test/log"
+			annotator, err := New(&config)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			annotated, err := annotator.AnnotateSource("test.go", []byte(stripTestCode))
+			if err != nil {
+				t.Fatalf("AnnotateSource failed: %v", err)
+			}
+
+			stripped, err := annotator.StripSource("test.go", annotated)
+			if err != nil {
+				t.Fatalf("StripSource failed: %v", err)
+			}
+
+			if string(stripped) != string(expected) {
+				t.Errorf("Stripped source does not match original:\n%s", stripped)
+			}
+		})
+	}
+}
+
+func TestStripSourceClosures(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Closures:   true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+func Apply(xs []int, f func(int) int) []int {
+	for i, x := range xs {
+		xs[i] = f(x)
+	}
+	return xs
+}
+
+func main() {
+	double := func(x int) int {
+		return 2 * x
+	}
+	Apply([]int{1, 2}, func(x int) int {
+		return double(x) + 1
+	})
+}
+`
+
+	annotated, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	stripped, err := annotator.StripSource("test.go", annotated)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+
+	if string(stripped) != testCode {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+}
+
+func TestStripSourceOneline(t *testing.T) {
+	testCode := `package main
+
+type ID int
+
+func (ID) String() string { return "id" }
+
+func (i *ID) Reset() { *i = 0 }
+
+func (i *ID) Set(v int) { *i = 0; *i += ID(v) }
+
+func Apply(f func()) { f() }
+
+func main() { Apply(func() { println("x") }) }
+
+func Empty() {}
+`
+
+	testCases := []struct {
+		name    string
+		options Options
+	}{
+		{"default", Options{}},
+		{"defer", Options{Defer: true}},
+		{"closures", Options{Closures: true}},
+		{"defer closures", Options{Defer: true, Closures: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := tc.options
+			options.ImportPath = "github.com/test/log"
+			annotator, err := New(&options)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			annotated, err := annotator.AnnotateSource("test.go", []byte(testCode))
+			if err != nil {
+				t.Fatalf("AnnotateSource failed: %v", err)
+			}
+
+			stripped, err := annotator.StripSource("test.go", annotated)
+			if err != nil {
+				t.Fatalf("StripSource failed: %v", err)
+			}
+			if string(stripped) != testCode {
+				t.Errorf("Stripped source does not match original:\n%s", stripped)
+			}
+		})
+	}
+}
+
+func TestStripSourceUnnamedParams(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Closures:   true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	testCode := `package main
+
+type T struct{}
+
+func (T) F(_ int, s string) {}
+
+func (_ *T) G(int, string) (_ int, err error) {
+	return 0, nil
+}
+
+var h = func(_, _ int, s string) {
+	println(s)
+}
+`
+
+	annotated, err := annotator.AnnotateSource("test.go", []byte(testCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	stripped, err := annotator.StripSource("test.go", annotated)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+
+	if string(stripped) != testCode {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+}
+
+// legacyAnnotated is the test code annotated by a release before panics were logged,
+// which ran the original body ahead of the deferred LogLeave.
+const legacyAnnotated = `package main
+
+import (
+	"fmt"
+	__log "github.com/test/log"
+)
+
+func Add(a, b int) int {
+	__traceID := __log.ID()
+	__log.
+		LogEnter(__traceID,
+
+			"Add", []any{a, b})
+	res1 := func() int {
+		return a + b
+	}()
+	defer func() {
+		__log.LogLeave(__traceID,
+			"Add",
+
+			[]any{a, b}, []any{res1})
+	}()
+	return res1
+}
+func Divide(a, b int) (q int, err error) {
+	__traceID := __log.ID()
+	__log.
+		LogEnter(__traceID,
+
+			"Divide", []any{a, b})
+	q, err = func() (q int, err error) {
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		q = a / b
+		return
+	}()
+	defer func() {
+		__log.LogLeave(__traceID,
+			"Divide",
+
+			[]any{a, b}, []any{q, err})
+	}()
+	return
+}
+
+func main() {
+	__traceID := __log.ID()
+	__log.
+		LogEnter(__traceID,
+
+			"main", []any{})
+	defer func() {
+		__log.LogLeave(__traceID,
+			"main",
+
+			[]any{}, []any{})
+	}()
+
+	fmt.Println(Add(1, 2))
+}
+`
+
+func TestStripSourceLegacy(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	expected := `package main
+
+import "fmt"
+
+func Add(a, b int) int {
+	return a + b
+}
+func Divide(a, b int) (q int, err error) {
+	if b == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	q = a / b
+	return
+}
+
+func main() {
+
+	fmt.Println(Add(1, 2))
+}
+`
+
+	stripped, err := annotator.StripSource("test.go", []byte(legacyAnnotated))
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+	if string(stripped) != expected {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+
+	reannotated, err := annotator.AnnotateSource("test.go", []byte(legacyAnnotated))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+	annotated, err := annotator.AnnotateSource("test.go", []byte(expected))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+	if string(reannotated) != string(annotated) {
+		t.Errorf("Re-annotation differs from annotating the original:\n%s", reannotated)
+	}
+}
+
+func TestStripSourceModified(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	annotated, err := annotator.AnnotateSource("test.go", []byte(stripTestCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	// Log an extra value in the enter event of Add.
+	modified := strings.Replace(string(annotated), `"Add", []any{a, b}`, `"Add", []any{a, b, 42}`, 1)
+	if modified == string(annotated) {
+		t.Fatal("Failed to modify annotated source")
+	}
+
+	_, err = annotator.StripSource("test.go", []byte(modified))
+	if !errors.Is(err, errModified) {
+		t.Fatalf("Expected errModified, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "function Add") {
+		t.Errorf("Error does not name the modified function: %v", err)
+	}
+}
+
+func TestAnnotateSourceIdempotent(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	once, err := annotator.AnnotateSource("test.go", []byte(stripTestCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	twice, err := annotator.AnnotateSource("test.go", once)
+	if err != nil {
+		t.Fatalf("AnnotateSource on annotated source failed: %v", err)
+	}
+
+	if string(once) != string(twice) {
+		t.Errorf("Re-annotation changed the source:\n%s", twice)
+	}
+}
+
+func TestAnnotateSourceConfigChange(t *testing.T) {
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	annotated, err := annotator.AnnotateSource("test.go", []byte(stripTestCode))
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	reannotator, err := New(&Options{
+		ImportPath:  "github.com/other/log",
+		ShowPackage: true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := reannotator.AnnotateSource("test.go", annotated)
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	resultStr := string(result)
+	if count := strings.Count(resultStr, "__traceID := __log.ID()"); count != 5 {
+		t.Errorf("Expected 5 instrumented functions, got %d", count)
+	}
+	if !strings.Contains(resultStr, `"main_Add"`) {
+		t.Error("Instrumentation was not regenerated with the package prefix")
+	}
+	if strings.Contains(resultStr, "github.com/test/log") || !strings.Contains(resultStr, `__log "github.com/other/log"`) {
+		t.Error("Log import was not replaced")
+	}
+}
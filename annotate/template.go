@@ -0,0 +1,147 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"text/template"
+)
+
+// TemplateData is the data of the enter and leave templates for an instrumented
+// function. The embedded function is its manifest entry.
+type TemplateData struct {
+	Function
+	// Event is the Go expression of the event name, e.g. "main_Add".
+	Event string
+	// ArgValues and ResultValues are the comma-separated expressions of the logged
+	// receiver and parameter values and of the result values. Redacted values are
+	// replaced with a placeholder and, in type-checked mode, values of named types
+	// are converted to their underlying type.
+	ArgValues    string
+	ResultValues string
+	// Position is the position of the function, e.g. main.go:5:1.
+	Position   string
+	Timing     bool
+	ShowReturn bool
+}
+
+// sampleData is the template data of a function used to check templates.
+var sampleData = TemplateData{
+	Function: Function{
+		Name:    "main_Add",
+		Package: "main",
+		File:    "main.go",
+		Line:    5,
+		Params:  []Var{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		Results: []Var{{Name: "res1", Type: "int"}},
+	},
+	Event:        `"main_Add"`,
+	ArgValues:    "a, b",
+	ResultValues: "res1",
+	Position:     "main.go:5:1",
+}
+
+// parseTemplates parses the enter and leave templates, which the text defines as
+// "enter" and "leave". Without text, the built-in templates are used.
+func parseTemplates(text string) (*template.Template, *template.Template, error) {
+	if text == "" {
+		enter, err := template.New("enter").Parse(enterTmpl)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse enter template: %w", err)
+		}
+
+		leave, err := template.New("leave").Parse(leaveTmpl)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse leave template: %w", err)
+		}
+
+		return enter, leave, nil
+	}
+
+	t, err := template.New("").Parse(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	enter, leave := t.Lookup("enter"), t.Lookup("leave")
+	if enter == nil || leave == nil {
+		return nil, nil, errors.New(`templates must define "enter" and "leave"`)
+	}
+
+	// Check the templates on a sample function, so that mistakes are not reported
+	// for every function. The log import is added to every annotated file.
+	enterStr, leaveStr, err := executeTemplates(enter, leave, sampleData)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmts, err := parseStmts(enterStr + leaveStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !refersTo(stmts, importName) {
+		return nil, nil, fmt.Errorf("templates must call the log package as %s", importName)
+	}
+
+	return enter, leave, nil
+}
+
+// executeTemplates executes the enter and leave templates for a function. The
+// statements are inserted into the source as text, so they must be valid.
+func executeTemplates(enter, leave *template.Template, data TemplateData) (string, string, error) {
+	var enterBuf, leaveBuf bytes.Buffer
+	if err := enter.Execute(&enterBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute enter template: %w", err)
+	}
+	if err := leave.Execute(&leaveBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute leave template: %w", err)
+	}
+
+	if _, err := parseStmts(enterBuf.String() + leaveBuf.String()); err != nil {
+		return "", "", fmt.Errorf("invalid instrumentation: %w", err)
+	}
+
+	return enterBuf.String(), leaveBuf.String(), nil
+}
+
+// parseStmts parses a list of statements.
+func parseStmts(src string) ([]ast.Stmt, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p; func _() {"+src+"\n}", 0)
+	if err != nil {
+		return nil, err
+	}
+	return f.Decls[0].(*ast.FuncDecl).Body.List, nil
+}
+
+// refersTo reports whether statements refer to an identifier.
+func refersTo(stmts []ast.Stmt, name string) bool {
+	found := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
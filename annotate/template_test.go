@@ -0,0 +1,210 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+const traceTemplate = `
+{{define "enter"}}
+__span := __log.Start({{.Event}}, {{printf "%q" .PackagePath}}, {{printf "%q" .Position}}
+{{- range .Params}}, {{printf "%q" (print .Name " " .Type)}}{{end}}){{end}}
+{{define "leave"}}
+defer func() { __span.End({{if .ShowReturn}}{{.ResultValues}}{{end}}) }(){{end}}
+`
+
+func TestParseTemplates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{"valid", traceTemplate, ""},
+		{"missing leave", `{{define "enter"}}__log.Start(){{end}}`, `must define "enter" and "leave"`},
+		{"syntax", `{{define "enter"}}{{.Event{{end}}`, "failed to parse templates"},
+		{"unknown field", `{{define "enter"}}__log.Start({{.Nope}}){{end}}{{define "leave"}}{{end}}`, "can't evaluate field Nope"},
+		{"invalid statements", `{{define "enter"}}__log.Start({{end}}{{define "leave"}}{{end}}`, "invalid instrumentation"},
+		{"no log call", `{{define "enter"}}_ = 1{{end}}{{define "leave"}}{{end}}`, "must call the log package as __log"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(&Options{ImportPath: "example.com/trace", Template: tc.template})
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("New failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestAnnotateTemplate(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/svc\n\ngo 1.22\n",
+		"svc.go": "package svc\n\ntype UserID int64\n\nfunc Get(id UserID, name string) (string, error) {\n\treturn name, nil\n}\n",
+	})
+
+	annotator, err := New(&Options{
+		ImportPath:   "example.com/trace",
+		ShowReturn:   true,
+		Types:        true,
+		FormatLength: 1024,
+		Template:     traceTemplate,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	pkgs, err := annotator.LoadPackages(dir, ".")
+	if err != nil {
+		t.Fatalf("LoadPackages failed: %v", err)
+	}
+	results, err := annotator.AnnotatePackages(pkgs...)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("AnnotatePackages failed: %v, %v", results, err)
+	}
+
+	output := string(results[0].Output)
+	for _, want := range []string{
+		`__log "example.com/trace"`,
+		`__span := __log.Start("Get", "example.com/svc", "` + results[0].Filename + `:5:1", "id UserID", "name string")`,
+		`defer func() { __span.End(res1, res2) }()`,
+		`res1, res2 = func() (string, error) {`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "SetFormatLength") {
+		t.Errorf("Output configures the built-in logger:\n%s", output)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", output, 0); err != nil {
+		t.Errorf("Output does not parse: %v", err)
+	}
+
+	// Custom instrumentation ends with a marker, so it is stripped and generated again.
+	source, err := os.ReadFile(results[0].Filename)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	stripped, err := annotator.StripSource("svc.go", results[0].Output)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+	if string(stripped) != string(source) {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+
+	reannotated, err := annotator.AnnotateSource(results[0].Filename, results[0].Output)
+	if err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+	if string(reannotated) != output {
+		t.Errorf("Annotating again changed the output:\n%s", reannotated)
+	}
+}
+
+func TestStripTemplate(t *testing.T) {
+	testCode := `package main
+
+type ID int
+
+func (ID) String() string { return "id" }
+
+func (i *ID) Reset() { *i = 0 }
+
+func Div(a, b int) (q int, err error) {
+	q = a / b
+	return
+}
+
+func Empty() {}
+
+func Commented() {
+	// nothing
+}
+
+func main() {
+	f := func() {
+		println("x")
+	}
+	f()
+}
+`
+
+	for _, deferMode := range []bool{false, true} {
+		annotator, err := New(&Options{
+			ImportPath: "example.com/trace",
+			Closures:   true,
+			Defer:      deferMode,
+			Template:   traceTemplate,
+		})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+
+		annotated, err := annotator.AnnotateSource("test.go", []byte(testCode))
+		if err != nil {
+			t.Fatalf("AnnotateSource failed: %v", err)
+		}
+
+		// Stripping needs no templates.
+		stripper, err := New(&Options{Strip: true})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		stripped, err := stripper.StripSource("test.go", annotated)
+		if err != nil {
+			t.Fatalf("StripSource failed with defer %v: %v", deferMode, err)
+		}
+		if string(stripped) != testCode {
+			t.Errorf("Stripped source does not match original with defer %v:\n%s", deferMode, stripped)
+		}
+	}
+}
+
+func TestTemplateLineDirectives(t *testing.T) {
+	template := `
+{{define "enter"}}
+__id := __log.ID(){{end}}
+{{define "leave"}}
+defer func() { _, _ = __id, []any{ {{- .ResultValues -}} } }(){{end}}
+`
+
+	for _, deferMode := range []bool{false, true} {
+		output := runAnnotated(t, Options{
+			LineDirectives: true,
+			Defer:          deferMode,
+			Template:       template,
+		}, lineTestCode)
+
+		// runtime.Caller is called on lines 9 and 15 of lineTestCode.
+		if output != "9 15" {
+			t.Errorf("Expected original lines 9 15 with defer %v, got %q", deferMode, output)
+		}
+	}
+}
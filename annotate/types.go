@@ -0,0 +1,203 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typedLoadMode is the package information needed in type-checked mode.
+const typedLoadMode = loadMode | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo
+
+// typeInfo holds the type-checked signatures of the functions declared in a file,
+// keyed by their qualified names as returned by funcName.
+type typeInfo struct {
+	pkg   *types.Package
+	funcs map[string]*types.Signature
+}
+
+// AddTypes records the import path of a package and the signatures of its functions
+// if it was loaded with typedLoadMode, which are then used when annotating its files.
+func (a *Annotator) AddTypes(pkg *packages.Package) {
+	for _, file := range pkg.GoFiles {
+		a.packagePaths[file] = pkg.PkgPath
+	}
+
+	if pkg.TypesInfo == nil {
+		return
+	}
+
+	for _, f := range pkg.Syntax {
+		ti := &typeInfo{
+			pkg:   pkg.Types,
+			funcs: make(map[string]*types.Signature),
+		}
+
+		ambiguous := make(map[string]bool)
+		for _, decl := range f.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+
+			fn, ok := pkg.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+
+			// Names such as init may be declared more than once.
+			name := funcName(funcDecl)
+			if _, ok := ti.funcs[name]; ok {
+				ambiguous[name] = true
+			}
+			ti.funcs[name] = fn.Type().(*types.Signature)
+		}
+		for name := range ambiguous {
+			delete(ti.funcs, name)
+		}
+
+		a.types[pkg.Fset.File(f.Pos()).Name()] = ti
+	}
+}
+
+// typesFor returns the type information of a file, or nil if it was not type-checked.
+func (a *Annotator) typesFor(filename string) *typeInfo {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil
+	}
+	return a.types[abs]
+}
+
+// packagePathFor returns the import path of the package of a file, or "" if the
+// file was not loaded as part of a package.
+func (a *Annotator) packagePathFor(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return ""
+	}
+	return a.packagePaths[abs]
+}
+
+// signature returns the signature of a function declaration, or nil if it is unknown.
+func (ti *typeInfo) signature(decl *ast.FuncDecl) *types.Signature {
+	if ti == nil {
+		return nil
+	}
+	return ti.funcs[funcName(decl)]
+}
+
+// addTypes records the static types of the receiver, parameters and results of a
+// function, and the conversions that let the logger format values of named types
+// without reflection.
+func (info *functionInfo) addTypes(sig *types.Signature, pkg *types.Package) {
+	qualifier := types.RelativeTo(pkg)
+	info.Conversions = make(map[string]string)
+
+	add := func(names []string, vars []*types.Var) []string {
+		var typeNames []string
+		for i, v := range vars {
+			if i >= len(names) {
+				break
+			}
+			typeNames = append(typeNames, types.TypeString(v.Type(), qualifier))
+			if conv := formatConversion(v.Type()); conv != "" {
+				info.Conversions[names[i]] = conv
+			}
+		}
+		return typeNames
+	}
+
+	if recv := sig.Recv(); recv != nil {
+		info.ReceiverTypes = add(info.ReceiverNames, []*types.Var{recv})
+	}
+	info.ArgTypes = add(info.ArgNames, tupleVars(sig.Params()))
+	info.RetTypes = add(info.RetNames, tupleVars(sig.Results()))
+}
+
+// tupleVars returns the variables of a tuple.
+func tupleVars(tuple *types.Tuple) []*types.Var {
+	vars := make([]*types.Var, tuple.Len())
+	for i := range vars {
+		vars[i] = tuple.At(i)
+	}
+	return vars
+}
+
+// formatConversion returns the type to which a value of type t is converted before
+// logging, or "" if it is logged as is. Named types with a basic, byte slice or byte
+// array underlying type are converted to it, so that the logger can use its fast paths.
+// Types with their own String, Error or Format method keep their formatting.
+func formatConversion(t types.Type) string {
+	if _, ok := types.Unalias(t).(*types.Named); !ok {
+		return ""
+	}
+
+	methods := types.NewMethodSet(t)
+	for _, name := range []string{"String", "Error", "Format"} {
+		if methods.Lookup(nil, name) != nil {
+			return ""
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&(types.IsInteger|types.IsFloat|types.IsString|types.IsBoolean) != 0 && u.Info()&types.IsUntyped == 0 {
+			return u.Name()
+		}
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			return "[]byte"
+		}
+	case *types.Array:
+		if isByte(u.Elem()) {
+			return types.TypeString(u, nil)
+		}
+	}
+
+	return ""
+}
+
+// isByte reports whether t is byte or uint8.
+func isByte(t types.Type) bool {
+	basic, ok := t.(*types.Basic)
+	return ok && basic.Kind() == types.Uint8
+}
+
+// signatureString formats the typed signature of a function for the monitoring rules,
+// e.g. (c *Conn, p []byte) (res1 int, res2 error).
+func (info *functionInfo) signatureString() string {
+	join := func(names, types []string) string {
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name
+			if i < len(types) {
+				fields[i] += " " + types[i]
+			}
+		}
+		return strings.Join(fields, ", ")
+	}
+
+	args := join(append(append([]string(nil), info.ReceiverNames...), info.ArgNames...), append(append([]string(nil), info.ReceiverTypes...), info.ArgTypes...))
+	return "(" + args + ") (" + join(info.RetNames, info.RetTypes) + ")"
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestFormatConversion(t *testing.T) {
+	src := `package p
+
+import "time"
+
+type UserID int64
+type Name string
+type Hash []byte
+type Digest [32]byte
+type Level int
+type Ratio float64
+type Point struct{ X, Y int }
+type Alias = UserID
+
+func (l Level) String() string { return "" }
+
+var (
+	a UserID
+	b Name
+	c Hash
+	d Digest
+	e Level
+	f Ratio
+	g Point
+	h time.Duration
+	i int
+	j Alias
+)
+`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("Failed to type-check: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"a", "int64"},
+		{"b", "string"},
+		{"c", "[]byte"},
+		{"d", "[32]byte"},
+		{"e", ""},
+		{"f", "float64"},
+		{"g", ""},
+		{"h", ""},
+		{"i", ""},
+		{"j", "int64"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			typ := pkg.Scope().Lookup(tc.name).Type()
+			if conv := formatConversion(typ); conv != tc.expected {
+				t.Errorf("Expected conversion %q for %s, got %q", tc.expected, typ, conv)
+			}
+		})
+	}
+}
+
+func TestAnnotatePackageTypes(t *testing.T) {
+	source := `package svc
+
+type UserID int64
+
+type Conn struct{}
+
+func (c *Conn) Lookup(id UserID, name string) (UserID, error) {
+	return id, nil
+}
+`
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/svc\n\ngo 1.22\n",
+		"svc.go": source,
+	})
+
+	annotator, err := New(&Options{
+		ImportPath: "github.com/test/log",
+		Types:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	pkgs, err := annotator.LoadPackages(dir, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages failed: %v", err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("Expected 1 package, got %d", len(pkgs))
+	}
+	results, err := annotator.AnnotatePackage(pkgs[0])
+	if err != nil {
+		t.Fatalf("AnnotatePackage failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+
+	annotated := results[0].Output
+	if !strings.Contains(string(annotated), `[]any{c, int64(id), name}`) {
+		t.Errorf("Named type is not converted for logging:\n%s", annotated)
+	}
+	if !strings.Contains(string(annotated), `[]any{int64(res1), res2}`) {
+		t.Errorf("Named result type is not converted for logging:\n%s", annotated)
+	}
+
+	manifest := annotator.Manifest()
+	if len(manifest) != 1 || manifest[0].Signature != "(c *Conn, id UserID, name string) (res1 UserID, res2 error)" {
+		t.Errorf("Unexpected manifest %v", manifest)
+	}
+	if len(manifest) == 1 {
+		fn := manifest[0]
+		if fn.PackagePath != "example.com/svc" || fn.Receiver[0].Type != "*Conn" || fn.Params[0].Type != "UserID" || fn.Results[1].Type != "error" {
+			t.Errorf("Manifest does not record the package and types: %+v", fn)
+		}
+	}
+	if theory := GenerateTheory(manifest); !strings.Contains(theory, "// (c *Conn, id UserID, name string) (res1 UserID, res2 error)\nrule Svc_Conn_Lookup ") {
+		t.Errorf("Theory does not record the types:\n%s", theory)
+	}
+
+	stripped, err := annotator.StripSource("svc.go", annotated)
+	if err != nil {
+		t.Fatalf("StripSource failed: %v", err)
+	}
+	if string(stripped) != source {
+		t.Errorf("Stripped source does not match original:\n%s", stripped)
+	}
+}
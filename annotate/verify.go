@@ -0,0 +1,129 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Verify type-checks the packages of the results with their processed sources, so that
+// they can be checked before anything is written. The files must be on disk. The
+// diagnostics refer to the original sources and name the function they occur in.
+func (a *Annotator) Verify(results []*Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	overlay := make(map[string][]byte)
+	sourceMaps := make(map[string]*sourceMap)
+	var queries []string
+	for _, r := range results {
+		abs, err := filepath.Abs(r.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path %s: %w", r.Filename, err)
+		}
+		overlay[abs] = r.Output
+		queries = append(queries, "file="+abs)
+
+		if r.base == nil {
+			continue
+		}
+		f, err := parser.ParseFile(a.fset, r.Filename, r.base, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		m, err := a.newSourceMap(r.Filename, f, r.Output, r.generated)
+		if err != nil {
+			return err
+		}
+		sourceMaps[abs] = m
+	}
+
+	cfg := &packages.Config{
+		Mode:    typedLoadMode,
+		Overlay: overlay,
+	}
+	if a.config.BuildTags != "" {
+		cfg.BuildFlags = []string{"-tags=" + a.config.BuildTags}
+	}
+
+	pkgs, err := packages.Load(cfg, queries...)
+	if err != nil {
+		return fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	var errs []error
+	for _, pkg := range pkgs {
+		for _, typeErr := range pkg.TypeErrors {
+			errs = append(errs, a.diagnostic(typeErr, sourceMaps))
+		}
+		for _, pkgErr := range pkg.Errors {
+			if pkgErr.Kind != packages.TypeError {
+				errs = append(errs, pkgErr)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// diagnostic maps a type error in an annotated file back to the original source. Errors
+// in the instrumentation are reported at the function that it was added to.
+func (a *Annotator) diagnostic(typeErr types.Error, sourceMaps map[string]*sourceMap) error {
+	pos := typeErr.Fset.PositionFor(typeErr.Pos, false)
+	m, ok := sourceMaps[pos.Filename]
+	if !ok {
+		return fmt.Errorf("%s: %s", pos, typeErr.Msg)
+	}
+
+	file := a.fset.File(m.out.Pos())
+	offset := file.Pos(pos.Offset)
+	orig := token.Position{Filename: m.filename, Line: m.line(pos.Line)}
+
+	decl := enclosingFunc(m.out, offset)
+	if decl == nil {
+		return fmt.Errorf("%s: %s", orig, typeErr.Msg)
+	}
+
+	err := errors.New(typeErr.Msg)
+	if m.inInstrumentation(offset) {
+		orig.Line = m.line(a.fset.PositionFor(decl.Pos(), false).Line)
+		err = fmt.Errorf("in instrumentation: %s", typeErr.Msg)
+	}
+
+	return &FuncError{Pos: orig, Func: funcName(decl), Err: err}
+}
+
+// enclosingFunc returns the function declaration of a file that contains a position.
+func enclosingFunc(f *ast.File, pos token.Pos) *ast.FuncDecl {
+	for _, decl := range f.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Pos() <= pos && pos < funcDecl.End() {
+			return funcDecl
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package annotate
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	code := `package main
+
+import "fmt"
+
+// Bad reuses a name of the instrumentation.
+func Bad() int {
+	__traceID := 1
+	return __traceID
+}
+
+func Shadow(__log int) {
+	fmt.Println(__log)
+}
+
+func main() {
+	fmt.Println(Bad())
+}
+`
+	dir := writeLogModule(t, map[string]string{"main.go": code})
+	file := filepath.Join(dir, "main.go")
+
+	annotator, err := New(&Options{
+		ImportPath: "github.com/specmon/go-annotate/log",
+		Defer:      true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := annotator.AnnotateFile(file)
+	if err != nil {
+		t.Fatalf("AnnotateFile failed: %v", err)
+	}
+
+	err = annotator.Verify([]*Result{result})
+	if err == nil {
+		t.Fatal("Verification of conflicting names succeeded")
+	}
+
+	var funcErr *FuncError
+	if !errors.As(err, &funcErr) || funcErr.Func != "Bad" || funcErr.Pos.Line != 7 {
+		t.Errorf("Error is not located at the original statement: %v", err)
+	}
+	if !strings.Contains(err.Error(), "main.go:11: function Shadow: in instrumentation:") {
+		t.Errorf("Error in the instrumentation is not located at the function: %v", err)
+	}
+}
+
+func TestVerifyValid(t *testing.T) {
+	dir := writeLogModule(t, map[string]string{
+		"main.go": "package main\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc main() {\n\tprintln(Add(1, 2))\n}\n",
+	})
+
+	annotator, err := New(&Options{
+		ImportPath: "github.com/specmon/go-annotate/log",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := annotator.AnnotateFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("AnnotateFile failed: %v", err)
+	}
+	if !strings.Contains(string(result.Output), "LogEnter") {
+		t.Fatal("File was not annotated")
+	}
+	if err := annotator.Verify([]*Result{result}); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
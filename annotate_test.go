@@ -1,359 +0,0 @@
-// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
-// Author: Kevin Morio <kevin.morio@cispa.de>
-//
-// This file is part of go-annotate.
-//
-// This program is free software: you can redistribute it and/or modify
-// it under the terms of the MIT License as published by
-// the Open Source Initiative.
-//
-// This program is distributed in the hope that it will be useful,
-// but WITHOUT ANY WARRANTY; without even the implied warranty of
-// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
-// MIT License for more details.
-//
-// You should have received a copy of the MIT License
-// along with this program. If not, see <https://opensource.org/licenses/MIT>.
-
-package main
-
-import (
-	"bytes"
-	"os"
-	"strings"
-	"testing"
-)
-
-func TestNewAnnotator(t *testing.T) {
-	config := &Config{
-		ImportPath: "github.com/test/log",
-	}
-
-	annotator, err := NewAnnotator(config)
-	if err != nil {
-		t.Fatalf("NewAnnotator failed: %v", err)
-	}
-
-	if annotator == nil {
-		t.Fatal("NewAnnotator returned nil")
-	}
-
-	if annotator.config != config {
-		t.Error("Annotator config not set correctly")
-	}
-
-	if annotator.fset == nil {
-		t.Error("FileSet not initialized")
-	}
-
-	if annotator.enterTemplate == nil {
-		t.Error("Enter template not initialized")
-	}
-
-	if annotator.leaveTemplate == nil {
-		t.Error("Leave template not initialized")
-	}
-}
-
-func TestAnnotateSource(t *testing.T) {
-	config := &Config{
-		ImportPath: "github.com/test/log",
-	}
-
-	annotator, err := NewAnnotator(config)
-	if err != nil {
-		t.Fatalf("NewAnnotator failed: %v", err)
-	}
-
-	testCode := `package main
-
-import "fmt"
-
-func Add(a, b int) int {
-	return a + b
-}
-
-func main() {
-	result := Add(5, 10)
-	fmt.Printf("Result: %d\n", result)
-}`
-
-	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
-	if err != nil {
-		t.Fatalf("AnnotateSource failed: %v", err)
-	}
-
-	resultStr := string(result)
-
-	// Check that the log import was added
-	if !strings.Contains(resultStr, `__log "github.com/test/log"`) {
-		t.Error("Log import not added correctly")
-	}
-
-	// Check that function instrumentation was added
-	if !strings.Contains(resultStr, "__traceID := __log.ID()") {
-		t.Error("Function entry instrumentation not added")
-	}
-
-	if !strings.Contains(resultStr, "LogEnter") {
-		t.Error("LogEnter call not added")
-	}
-
-	if !strings.Contains(resultStr, "LogLeave") {
-		t.Error("LogLeave call not added")
-	}
-}
-
-func TestAnnotateSourceExportedOnly(t *testing.T) {
-	config := &Config{
-		ImportPath:   "github.com/test/log",
-		ExportedOnly: true,
-	}
-
-	annotator, err := NewAnnotator(config)
-	if err != nil {
-		t.Fatalf("NewAnnotator failed: %v", err)
-	}
-
-	testCode := `package main
-
-func Add(a, b int) int {
-	return a + b
-}
-
-func privateFunc() {
-	// This should not be instrumented
-}`
-
-	result, err := annotator.AnnotateSource("test.go", []byte(testCode))
-	if err != nil {
-		t.Fatalf("AnnotateSource failed: %v", err)
-	}
-
-	resultStr := string(result)
-
-	// Add should be instrumented (exported) - check for LogEnter instead
-	if !strings.Contains(resultStr, "LogEnter") || !strings.Contains(resultStr, "Add") {
-		t.Error("Exported function not instrumented")
-	}
-
-	// Count occurrences to ensure only one function is instrumented
-	logEnterCount := strings.Count(resultStr, "LogEnter")
-	logLeaveCount := strings.Count(resultStr, "LogLeave")
-	if logEnterCount != 1 || logLeaveCount != 1 {
-		t.Errorf("Expected 1 LogEnter and 1 LogLeave for Add function, got %d LogEnter and %d LogLeave", logEnterCount, logLeaveCount)
-	}
-
-	// Verify privateFunc was not instrumented by checking it doesn't have logging calls
-	privateFuncLines := strings.Split(resultStr, "\n")
-	inPrivateFunc := false
-	for _, line := range privateFuncLines {
-		if strings.Contains(line, "func privateFunc()") {
-			inPrivateFunc = true
-		} else if inPrivateFunc && strings.Contains(line, "func ") {
-			inPrivateFunc = false
-		} else if inPrivateFunc && (strings.Contains(line, "LogEnter") || strings.Contains(line, "LogLeave")) {
-			t.Error("Private function was incorrectly instrumented")
-			break
-		}
-	}
-}
-
-func TestParamNames(t *testing.T) {
-	testCases := []struct {
-		name     string
-		code     string
-		expected []string
-	}{
-		{
-			name:     "simple parameters",
-			code:     "func test(a int, b string)",
-			expected: []string{"a", "b"},
-		},
-		{
-			name:     "no parameters",
-			code:     "func test()",
-			expected: []string{},
-		},
-		{
-			name:     "grouped parameters",
-			code:     "func test(a, b int, c string)",
-			expected: []string{"a", "b", "c"},
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			// This would require more complex AST parsing setup
-			// Simplified test for basic functionality
-			if len(tc.expected) == 0 {
-				// Test empty case
-				result := paramNames(nil)
-				if len(result) != 0 {
-					t.Errorf("Expected empty result, got %v", result)
-				}
-			}
-		})
-	}
-}
-
-func TestFuncName(t *testing.T) {
-	testCases := []struct {
-		name     string
-		receiver string
-		funcName string
-		expected string
-	}{
-		{
-			name:     "simple function",
-			receiver: "",
-			funcName: "Add",
-			expected: "Add",
-		},
-		{
-			name:     "method with pointer receiver",
-			receiver: "*MyStruct",
-			funcName: "Method",
-			expected: "MyStruct_Method",
-		},
-		{
-			name:     "method with value receiver",
-			receiver: "MyStruct",
-			funcName: "Method",
-			expected: "MyStruct_Method",
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			// This test would require creating AST nodes
-			// Simplified verification that separator is used correctly
-			if tc.receiver != "" {
-				expected := strings.Contains(tc.expected, separator)
-				if !expected {
-					t.Errorf("Expected separator in method name")
-				}
-			}
-		})
-	}
-}
-
-func TestAnnotateFile(t *testing.T) {
-	// Create temporary test file
-	testContent := `package main
-
-import "fmt"
-
-func Hello() {
-	fmt.Println("Hello, World!")
-}
-
-func main() {
-	Hello()
-}`
-
-	tmpFile, err := os.CreateTemp("", "test_*.go")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-
-	if _, err := tmpFile.Write([]byte(testContent)); err != nil {
-		t.Fatalf("Failed to write test content: %v", err)
-	}
-	tmpFile.Close()
-
-	config := &Config{
-		ImportPath: "github.com/test/log",
-		WriteFiles: false, // Don't write back to test file
-	}
-
-	annotator, err := NewAnnotator(config)
-	if err != nil {
-		t.Fatalf("NewAnnotator failed: %v", err)
-	}
-
-	// Capture stdout
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	err = annotator.AnnotateFile(tmpFile.Name())
-	if err != nil {
-		t.Fatalf("AnnotateFile failed: %v", err)
-	}
-
-	w.Close()
-	os.Stdout = old
-
-	var buf bytes.Buffer
-	_, _ = buf.ReadFrom(r)
-	output := buf.String()
-
-	// Verify instrumentation was added
-	if !strings.Contains(output, "LogEnter") {
-		t.Error("LogEnter not found in output")
-	}
-
-	if !strings.Contains(output, "LogLeave") {
-		t.Error("LogLeave not found in output")
-	}
-}
-
-// Benchmark tests.
-func BenchmarkAnnotateSource(b *testing.B) {
-	config := &Config{
-		ImportPath: "github.com/test/log",
-	}
-
-	annotator, err := NewAnnotator(config)
-	if err != nil {
-		b.Fatalf("NewAnnotator failed: %v", err)
-	}
-
-	testCode := []byte(`package main
-
-import "fmt"
-
-func Add(a, b int) int {
-	return a + b
-}
-
-func Multiply(a, b int) int {
-	return a * b
-}
-
-func Calculate(x, y int) (int, int) {
-	sum := Add(x, y)
-	product := Multiply(x, y)
-	return sum, product
-}
-
-func main() {
-	sum, product := Calculate(5, 10)
-	fmt.Printf("Sum: %d, Product: %d\n", sum, product)
-}`)
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := annotator.AnnotateSource("bench.go", testCode)
-		if err != nil {
-			b.Fatalf("AnnotateSource failed: %v", err)
-		}
-	}
-}
-
-func BenchmarkNewAnnotator(b *testing.B) {
-	config := &Config{
-		ImportPath: "github.com/test/log",
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		annotator, err := NewAnnotator(config)
-		if err != nil {
-			b.Fatalf("NewAnnotator failed: %v", err)
-		}
-		_ = annotator
-	}
-}
@@ -0,0 +1,337 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+// Config holds the command line configuration: the annotation options and the options
+// that select what is done with the processed files.
+type Config struct {
+	annotate.Options
+	GeneratePath string
+	ManifestPath string
+	WriteFiles   bool
+	List         bool
+	Diff         bool
+	OutputDir    string
+	OverlayDir   string
+	TemplateFile string
+	Verify       bool
+}
+
+// configFileName is the name of the configuration files looked up in the directory of
+// each target file and its parents.
+const configFileName = ".go-annotate.json"
+
+// settings are the annotation options of a configuration file, which can also be
+// overridden per package. Unset fields keep the value of the enclosing configuration.
+// The JSON keys are the names of the corresponding command line flags.
+type settings struct {
+	ImportPath   *string  `json:"import"`
+	ShowReturn   *bool    `json:"returns"`
+	ExportedOnly *bool    `json:"exported"`
+	Prefix       *string  `json:"prefix"`
+	ShowPackage  *bool    `json:"package"`
+	Timing       *bool    `json:"timing"`
+	Closures     *bool    `json:"closures"`
+	Defer        *bool    `json:"defer"`
+	Stack        *bool    `json:"stack"`
+	Include      []string `json:"include"`
+	Exclude      []string `json:"exclude"`
+	IncludeFiles []string `json:"include-files"`
+	ExcludeFiles []string `json:"exclude-files"`
+}
+
+// fileConfig is the content of a configuration file. Packages maps directories
+// relative to the configuration file, such as internal/crypto or internal/..., to
+// overrides for the packages in them. The format length is set for the whole process
+// by the instrumentation of every file, so packages cannot override it.
+type fileConfig struct {
+	settings
+	FormatLength *int                `json:"formatLength"`
+	GeneratePath *string             `json:"generate"`
+	ManifestPath *string             `json:"manifest"`
+	BuildTags    *string             `json:"tags"`
+	Types        *bool               `json:"types"`
+	Packages     map[string]settings `json:"packages"`
+
+	dir string
+}
+
+// projectConfig resolves the configuration of target files from the configuration
+// files above them and the command line, where explicitly set flags take precedence.
+// Files closer to the target override the keys they set in the files further up.
+type projectConfig struct {
+	flags    Config
+	explicit map[string]bool
+	// formatLength, if set, is the format length of all files, see fixFormatLength.
+	formatLength *int
+
+	mu    sync.Mutex // guards files, as files may be annotated concurrently
+	files map[string][]*fileConfig
+}
+
+// newProjectConfig creates a project configuration on top of the parsed command line.
+// The explicit set contains the names of the flags given on the command line.
+func newProjectConfig(flags Config, explicit map[string]bool) *projectConfig {
+	return &projectConfig{
+		flags:    flags,
+		explicit: explicit,
+		files:    make(map[string][]*fileConfig),
+	}
+}
+
+// configFor returns the configuration of a target file, which must name the log
+// package unless instrumentation is stripped.
+func (p *projectConfig) configFor(file string) (*Config, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := p.configForDir(filepath.Dir(abs))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ImportPath == "" && !config.Strip {
+		return nil, fmt.Errorf("no import path of the log package set for %s, use -import or %q in %s", file, "import", configFileName)
+	}
+
+	return config, nil
+}
+
+// optionsFor returns the annotation options of a target file.
+func (p *projectConfig) optionsFor(file string) (*annotate.Options, error) {
+	config, err := p.configFor(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.Options, nil
+}
+
+// configForDir returns the configuration of the files in a directory.
+func (p *projectConfig) configForDir(dir string) (*Config, error) {
+	config := p.flags
+
+	p.mu.Lock()
+	files, err := p.lookup(dir)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fc := range files {
+		if fc.FormatLength != nil && p.formatLength != nil && *fc.FormatLength != *p.formatLength && !p.explicit["formatLength"] {
+			return nil, fmt.Errorf("%s sets formatLength %d, but %d applies to the whole program",
+				filepath.Join(fc.dir, configFileName), *fc.FormatLength, *p.formatLength)
+		}
+		if err := fc.applyDir(&config, dir, p.explicit); err != nil {
+			return nil, err
+		}
+	}
+	if p.formatLength != nil {
+		config.FormatLength = *p.formatLength
+	}
+
+	if config.Timing {
+		config.ShowReturn = true
+	}
+
+	return &config, nil
+}
+
+// fixFormatLength makes the format length resolved for dir, the working directory,
+// the one of all files. Every instrumented package sets the limit for the whole
+// program, so configuration files elsewhere must not set a different one.
+func (p *projectConfig) fixFormatLength(dir string) error {
+	config, err := p.configForDir(dir)
+	if err != nil {
+		return err
+	}
+
+	p.formatLength = &config.FormatLength
+	return nil
+}
+
+// applyDir sets the options of config for the files in dir that are set in the
+// configuration file, including the overrides of the packages that contain dir, and
+// not given explicitly on the command line.
+func (fc *fileConfig) applyDir(config *Config, dir string, explicit map[string]bool) error {
+	fc.settings.apply(config, explicit)
+	if fc.FormatLength != nil && !explicit["formatLength"] {
+		config.FormatLength = *fc.FormatLength
+	}
+	if fc.GeneratePath != nil && !explicit["generate"] {
+		config.GeneratePath = *fc.GeneratePath
+	}
+	if fc.ManifestPath != nil && !explicit["manifest"] {
+		config.ManifestPath = *fc.ManifestPath
+	}
+	if fc.BuildTags != nil && !explicit["tags"] {
+		config.BuildTags = *fc.BuildTags
+	}
+	if fc.Types != nil && !explicit["types"] {
+		config.Types = *fc.Types
+	}
+
+	rel, err := filepath.Rel(fc.dir, dir)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range fc.packagePatterns() {
+		if matchPackageDir(pattern, filepath.ToSlash(rel)) {
+			s := fc.Packages[pattern]
+			s.apply(config, explicit)
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the configuration files in dir and its parents, from the outermost
+// to the closest. It must be called with p.mu held.
+func (p *projectConfig) lookup(dir string) ([]*fileConfig, error) {
+	if files, ok := p.files[dir]; ok {
+		return files, nil
+	}
+
+	var files []*fileConfig
+	if parent := filepath.Dir(dir); parent != dir {
+		var err error
+		files, err = p.lookup(parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(dir, configFileName)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		fc, err := parseFileConfig(path, data)
+		if err != nil {
+			return nil, err
+		}
+		fc.dir = dir
+		// The files of the parent are shared with its other subdirectories.
+		files = append(files[:len(files):len(files)], fc)
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	p.files[dir] = files
+	return files, nil
+}
+
+// parseFileConfig parses a configuration file, rejecting unknown keys.
+func parseFileConfig(path string, data []byte) (*fileConfig, error) {
+	var fc fileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for pattern := range fc.Packages {
+		if pattern == "" || filepath.IsAbs(pattern) || strings.HasPrefix(pattern, "..") {
+			return nil, fmt.Errorf("invalid config file %s: package %q is not a relative directory", path, pattern)
+		}
+	}
+
+	return &fc, nil
+}
+
+// packagePatterns returns the package patterns of the configuration file ordered from
+// the least to the most specific, so that more specific overrides are applied last.
+func (fc *fileConfig) packagePatterns() []string {
+	patterns := make([]string, 0, len(fc.Packages))
+	for pattern := range fc.Packages {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) < len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	return patterns
+}
+
+// matchPackageDir reports whether a slash-separated directory relative to the
+// configuration file matches a package pattern. A pattern ending in /... also matches
+// all directories below.
+func matchPackageDir(pattern, dir string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if base, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return base == "." || dir == base || strings.HasPrefix(dir, base+"/")
+	}
+
+	return dir == strings.TrimSuffix(pattern, "/")
+}
+
+// apply sets the options of config that are set in s and not given explicitly on the
+// command line.
+func (s *settings) apply(config *Config, explicit map[string]bool) {
+	setString := func(name string, dst *string, value *string) {
+		if value != nil && !explicit[name] {
+			*dst = *value
+		}
+	}
+	setBool := func(name string, dst *bool, value *bool) {
+		if value != nil && !explicit[name] {
+			*dst = *value
+		}
+	}
+	setList := func(name string, dst *[]string, value []string) {
+		if value != nil && !explicit[name] {
+			*dst = value
+		}
+	}
+
+	setString("import", &config.ImportPath, s.ImportPath)
+	setBool("returns", &config.ShowReturn, s.ShowReturn)
+	setBool("exported", &config.ExportedOnly, s.ExportedOnly)
+	setString("prefix", &config.Prefix, s.Prefix)
+	setBool("package", &config.ShowPackage, s.ShowPackage)
+	setBool("timing", &config.Timing, s.Timing)
+	setBool("closures", &config.Closures, s.Closures)
+	setBool("defer", &config.Defer, s.Defer)
+	setBool("stack", &config.Stack, s.Stack)
+	setList("include", &config.Include, s.Include)
+	setList("exclude", &config.Exclude, s.Exclude)
+	setList("include-files", &config.IncludeFiles, s.IncludeFiles)
+	setList("exclude-files", &config.ExcludeFiles, s.ExcludeFiles)
+}
@@ -0,0 +1,298 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+const testConfigFile = `{
+	"import": "example.com/log",
+	"package": true,
+	"timing": true,
+	"formatLength": 64,
+	"generate": "rules.thy",
+	"manifest": "manifest.json",
+	"packages": {
+		"internal/...": {"exported": true},
+		"internal/crypto": {"exclude": ["^seal"], "import": "example.com/crypto/log"}
+	}
+}`
+
+func TestProjectConfig(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName:               testConfigFile,
+		"main.go":                    "package main\n",
+		"internal/util/util.go":      "package util\n",
+		"internal/crypto/crypto.go":  "package crypto\n",
+		"tools/" + configFileName:    `{"import": "example.com/tools/log"}`,
+		"tools/gen/gen.go":           "package gen\n",
+		"internal/crypto/sub/x/x.go": "package x\n",
+		"internal/cryptography/c.go": "package cryptography\n",
+	})
+
+	project := newProjectConfig(Config{Options: annotate.Options{FormatLength: 1024, ExportedOnly: false}}, map[string]bool{"formatLength": true})
+
+	testCases := []struct {
+		file         string
+		importPath   string
+		exportedOnly bool
+		exclude      []string
+	}{
+		{"main.go", "example.com/log", false, nil},
+		{"internal/util/util.go", "example.com/log", true, nil},
+		{"internal/crypto/crypto.go", "example.com/crypto/log", true, []string{"^seal"}},
+		{"internal/crypto/sub/x/x.go", "example.com/log", true, nil},
+		{"internal/cryptography/c.go", "example.com/log", true, nil},
+		{"tools/gen/gen.go", "example.com/tools/log", false, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			config, err := project.configFor(filepath.Join(dir, tc.file))
+			if err != nil {
+				t.Fatalf("configFor failed: %v", err)
+			}
+
+			if config.ImportPath != tc.importPath {
+				t.Errorf("Expected import path %q, got %q", tc.importPath, config.ImportPath)
+			}
+			if config.ExportedOnly != tc.exportedOnly {
+				t.Errorf("Expected exported only %v, got %v", tc.exportedOnly, config.ExportedOnly)
+			}
+			if !reflect.DeepEqual(config.Exclude, tc.exclude) {
+				t.Errorf("Expected exclude %v, got %v", tc.exclude, config.Exclude)
+			}
+			if config.FormatLength != 1024 {
+				t.Errorf("Explicit flag was overridden: %d", config.FormatLength)
+			}
+		})
+	}
+
+	config, err := project.configFor(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if !config.ShowPackage || !config.Timing || !config.ShowReturn || config.GeneratePath != "rules.thy" || config.ManifestPath != "manifest.json" {
+		t.Errorf("Top-level settings not applied: %+v", config)
+	}
+
+	config, err = newProjectConfig(Config{}, nil).configFor(filepath.Join(dir, "internal/crypto/crypto.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.FormatLength != 64 {
+		t.Errorf("Top-level format length not applied: %d", config.FormatLength)
+	}
+}
+
+func TestProjectConfigExplicitFlags(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName:              testConfigFile,
+		"internal/crypto/crypto.go": "package crypto\n",
+	})
+
+	project := newProjectConfig(Config{Options: annotate.Options{ImportPath: "cli/log"}}, map[string]bool{"import": true, "exported": true})
+
+	config, err := project.configFor(filepath.Join(dir, "internal/crypto/crypto.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.ImportPath != "cli/log" || config.ExportedOnly {
+		t.Errorf("Explicit flags do not take precedence: %+v", config)
+	}
+}
+
+func TestProjectConfigNested(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName:                `{"import": "example.com/log", "exclude-files": ["*_gen.go"], "packages": {"sub/inner": {"exported": true}}}`,
+		"sub/" + configFileName:       `{"package": true}`,
+		"sub/s.go":                    "package sub\n\nfunc run() {}\n",
+		"sub/s_gen.go":                "package sub\n\nfunc generated() {}\n",
+		"sub/inner/" + configFileName: `{"import": "example.com/inner/log"}`,
+		"sub/inner/i.go":              "package inner\n",
+	})
+
+	project := newProjectConfig(Config{}, nil)
+
+	// Only the keys set in the nested file override those of the parent.
+	config, err := project.configFor(filepath.Join(dir, "sub/s.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.ImportPath != "example.com/log" || !config.ShowPackage || !reflect.DeepEqual(config.ExcludeFiles, []string{"*_gen.go"}) {
+		t.Errorf("Parent configuration not merged: %+v", config)
+	}
+
+	config, err = project.configFor(filepath.Join(dir, "sub/inner/i.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.ImportPath != "example.com/inner/log" || !config.ShowPackage || !config.ExportedOnly {
+		t.Errorf("Configuration files not merged top-down: %+v", config)
+	}
+
+	annotator, err := annotate.New(&annotate.Options{FileOptions: project.optionsFor})
+	if err != nil {
+		t.Fatalf("annotate.New failed: %v", err)
+	}
+	results, err := annotator.AnnotateFiles(filepath.Join(dir, "sub/s.go"), filepath.Join(dir, "sub/s_gen.go"))
+	if err != nil {
+		t.Fatalf("AnnotateFiles failed: %v", err)
+	}
+	if len(results) != 1 || !strings.HasSuffix(results[0].Filename, "s.go") {
+		t.Fatalf("Excluded file of the parent configuration was annotated: %v", results)
+	}
+	if output := string(results[0].Output); !strings.Contains(output, `__log "example.com/log"`) || !strings.Contains(output, `"sub_run"`) {
+		t.Errorf("Merged configuration not applied:\n%s", output)
+	}
+}
+
+func TestProjectConfigNoImport(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName: `{"package": true}`,
+		"main.go":      "package main\n",
+	})
+
+	_, err := newProjectConfig(Config{}, nil).configFor(filepath.Join(dir, "main.go"))
+	if err == nil || !strings.Contains(err.Error(), "no import path") {
+		t.Errorf("Expected missing import path error, got %v", err)
+	}
+
+	if _, err := newProjectConfig(Config{Options: annotate.Options{Strip: true}}, nil).configFor(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("Stripping requires no import path: %v", err)
+	}
+}
+
+func TestProjectConfigFormatLength(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName:          `{"import": "example.com/log", "formatLength": 64}`,
+		"a/" + configFileName:   `{"formatLength": 64, "exported": true}`,
+		"a/a.go":                "package a\n",
+		"b/" + configFileName:   `{"formatLength": 16}`,
+		"b/b.go":                "package b\n",
+		"sub/main.go":           "package main\n",
+		"sub/" + configFileName: `{"formatLength": 32}`,
+	})
+
+	project := newProjectConfig(Config{Options: annotate.Options{FormatLength: 1024}}, nil)
+	if err := project.fixFormatLength(dir); err != nil {
+		t.Fatalf("fixFormatLength failed: %v", err)
+	}
+
+	config, err := project.configFor(filepath.Join(dir, "a/a.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.FormatLength != 64 || !config.ExportedOnly {
+		t.Errorf("Configuration of a not applied: %+v", config)
+	}
+
+	_, err = project.configFor(filepath.Join(dir, "b/b.go"))
+	if err == nil || !strings.Contains(err.Error(), "formatLength 16, but 64 applies") {
+		t.Errorf("Expected conflicting format length error, got %v", err)
+	}
+
+	// The working directory sets the format length of all files.
+	project = newProjectConfig(Config{Options: annotate.Options{FormatLength: 1024}}, nil)
+	if err := project.fixFormatLength(filepath.Join(dir, "sub")); err != nil {
+		t.Fatalf("fixFormatLength failed: %v", err)
+	}
+	if _, err := project.configFor(filepath.Join(dir, "a/a.go")); err == nil {
+		t.Error("Expected conflicting format length error for a")
+	}
+
+	// An explicit flag overrides all files.
+	project = newProjectConfig(Config{Options: annotate.Options{FormatLength: 8}}, map[string]bool{"formatLength": true})
+	if err := project.fixFormatLength(dir); err != nil {
+		t.Fatalf("fixFormatLength failed: %v", err)
+	}
+	config, err = project.configFor(filepath.Join(dir, "b/b.go"))
+	if err != nil {
+		t.Fatalf("configFor failed: %v", err)
+	}
+	if config.FormatLength != 8 {
+		t.Errorf("Explicit format length not applied: %d", config.FormatLength)
+	}
+}
+
+func TestProjectConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown key", `{"imports": "x"}`, `unknown field "imports"`},
+		{"syntax", `{"import": }`, "invalid character"},
+		{"absolute package", `{"packages": {"/abs": {}}}`, "not a relative directory"},
+		{"package format length", `{"formatLength": 64, "packages": {"sub": {"formatLength": 5}}}`, `unknown field "formatLength"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeModule(t, map[string]string{
+				configFileName: tc.content,
+			})
+
+			_, err := newProjectConfig(Config{}, nil).configForDir(dir)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestAnnotateFileProjectConfig(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		configFileName:          testConfigFile,
+		"main.go":               "package main\n\nfunc run() {}\n",
+		"internal/util/util.go": "package util\n\nfunc helper() {}\n\nfunc Exported() {}\n",
+	})
+
+	project := newProjectConfig(Config{}, nil)
+	annotator, err := annotate.New(&annotate.Options{FileOptions: project.optionsFor})
+	if err != nil {
+		t.Fatalf("annotate.New failed: %v", err)
+	}
+
+	output := make(map[string]string)
+	for _, file := range []string{"main.go", "internal/util/util.go"} {
+		result, err := annotator.AnnotateFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("AnnotateFile failed: %v", err)
+		}
+		output[file] = string(result.Output)
+	}
+
+	if main := output["main.go"]; !strings.Contains(main, `"main_run"`) || !strings.Contains(main, `__log "example.com/log"`) {
+		t.Errorf("Configuration file not applied to main.go:\n%s", main)
+	}
+
+	if util := output["internal/util/util.go"]; strings.Contains(util, `"util_helper"`) || !strings.Contains(util, `"util_Exported"`) {
+		t.Errorf("Package override not applied to util.go:\n%s", util)
+	}
+
+	if manifest := annotator.Manifest(); len(manifest) != 2 {
+		t.Errorf("Expected 2 instrumented functions, got %d", len(manifest))
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffLine is a line of a diff, which is kept (' '), deleted ('-') or inserted ('+').
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns a unified diff of two versions of a file, or nil if they are equal.
+func unifiedDiff(oldName string, old []byte, newName string, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	lines := diffLines(splitLines(old), splitLines(new))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "diff %s %s\n--- %s\n+++ %s\n", oldName, newName, oldName, newName)
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// The hunk starts with context and extends until two changes are too far apart.
+		start := max(i-diffContext, 0)
+		oldLine -= i - start
+		newLine -= i - start
+		end := i
+		for kept := 0; end < len(lines) && kept <= 2*diffContext; end++ {
+			if lines[end].kind == ' ' {
+				kept++
+			} else {
+				kept = 0
+			}
+		}
+		end = min(lastChange(lines[:end])+1+diffContext, len(lines))
+
+		oldCount, newCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.kind != '+' {
+				oldCount++
+			}
+			if line.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, line := range lines[start:end] {
+			buf.WriteByte(line.kind)
+			buf.WriteString(line.text)
+			if len(line.text) == 0 || line.text[len(line.text)-1] != '\n' {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+
+	return buf.Bytes()
+}
+
+// hunkRange formats the start line and number of lines of a hunk.
+func hunkRange(line, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", line-1)
+	case 1:
+		return fmt.Sprintf("%d", line)
+	default:
+		return fmt.Sprintf("%d,%d", line, count)
+	}
+}
+
+// lastChange returns the index of the last deleted or inserted line.
+func lastChange(lines []diffLine) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].kind != ' ' {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// splitLines splits a file into lines that keep their line endings.
+func splitLines(src []byte) []string {
+	var lines []string
+	for len(src) > 0 {
+		i := bytes.IndexByte(src, '\n') + 1
+		if i == 0 {
+			i = len(src)
+		}
+		lines = append(lines, string(src[:i]))
+		src = src[i:]
+	}
+
+	return lines
+}
+
+// diffLines computes the lines of a diff from x to y. Like a patience diff, it aligns
+// the lines that occur exactly once on both sides first, which keeps the diff of
+// annotated code aligned with the original declarations.
+func diffLines(x, y []string) []diffLine {
+	var lines []diffLine
+	i, j := 0, 0
+	for _, m := range uniqueMatches(x, y) {
+		lines = appendGap(lines, x[i:m.x], y[j:m.y])
+		lines = append(lines, diffLine{' ', x[m.x]})
+		i, j = m.x+1, m.y+1
+	}
+
+	return appendGap(lines, x[i:], y[j:])
+}
+
+// appendGap appends the diff of the lines between two unique matches.
+func appendGap(lines []diffLine, x, y []string) []diffLine {
+	prefix := commonPrefix(x, y)
+	suffix := commonSuffix(x[prefix:], y[prefix:])
+	for _, line := range x[:prefix] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	midX, midY := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	if len(midX) > 0 && len(midY) > 0 && len(uniqueMatches(midX, midY)) > 0 {
+		lines = append(lines, diffLines(midX, midY)...)
+	} else {
+		for _, line := range midX {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range midY {
+			lines = append(lines, diffLine{'+', line})
+		}
+	}
+
+	for _, line := range x[len(x)-suffix:] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	return lines
+}
+
+// lineMatch pairs line x of one file with line y of the other.
+type lineMatch struct {
+	x, y int
+}
+
+// uniqueMatches returns the longest increasing sequence of pairs of lines that occur
+// exactly once in both x and y.
+func uniqueMatches(x, y []string) []lineMatch {
+	countX, countY := make(map[string]int), make(map[string]int)
+	index := make(map[string]int)
+	for i, line := range x {
+		countX[line]++
+		index[line] = i
+	}
+	for _, line := range y {
+		countY[line]++
+	}
+
+	var pairs []lineMatch
+	for j, line := range y {
+		if countX[line] == 1 && countY[line] == 1 {
+			pairs = append(pairs, lineMatch{index[line], j})
+		}
+	}
+
+	// Patience sorting finds the longest sequence with increasing x.
+	var piles []int
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		k := sort.Search(len(piles), func(k int) bool { return pairs[piles[k]].x > p.x })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = piles[k-1]
+		}
+		if k == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[k] = i
+		}
+	}
+
+	matches := make([]lineMatch, len(piles))
+	if len(piles) > 0 {
+		k := piles[len(piles)-1]
+		for i := len(matches) - 1; i >= 0; i-- {
+			matches[i] = pairs[k]
+			k = prev[k]
+		}
+	}
+
+	return matches
+}
+
+// commonPrefix returns the number of equal lines at the start of x and y.
+func commonPrefix(x, y []string) int {
+	n := 0
+	for n < len(x) && n < len(y) && x[n] == y[n] {
+		n++
+	}
+
+	return n
+}
+
+// commonSuffix returns the number of equal lines at the end of x and y.
+func commonSuffix(x, y []string) int {
+	n := 0
+	for n < len(x) && n < len(y) && x[len(x)-1-n] == y[len(y)-1-n] {
+		n++
+	}
+
+	return n
+}
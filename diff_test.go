@@ -0,0 +1,79 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+		},
+		{
+			name: "insertion",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "1\n2\n3\n4\nx\n5\n6\n7\n8\n",
+			expected: "diff a.orig a\n--- a.orig\n+++ a\n" +
+				"@@ -2,6 +2,7 @@\n 2\n 3\n 4\n+x\n 5\n 6\n 7\n",
+		},
+		{
+			name: "deletion at start",
+			old:  "x\n1\n2\n",
+			new:  "1\n2\n",
+			expected: "diff a.orig a\n--- a.orig\n+++ a\n" +
+				"@@ -1,3 +1,2 @@\n-x\n 1\n 2\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			expected: "diff a.orig a\n--- a.orig\n+++ a\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -8,3 +9,4 @@\n 8\n 9\n 10\n+11\n",
+		},
+		{
+			name: "merged hunks",
+			old:  "1\n2\n3\n4\n5\n",
+			new:  "1\nx\n3\n4\ny\n",
+			expected: "diff a.orig a\n--- a.orig\n+++ a\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+x\n 3\n 4\n-5\n+y\n",
+		},
+		{
+			name: "no newline at end",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			expected: "diff a.orig a\n--- a.orig\n+++ a\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := unifiedDiff("a.orig", []byte(tc.old), "a", []byte(tc.new))
+			if string(diff) != tc.expected {
+				t.Errorf("Unexpected diff:\n%s\nexpected:\n%s", diff, tc.expected)
+			}
+		})
+	}
+}
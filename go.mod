@@ -4,7 +4,11 @@ go 1.22.0
 
 require (
 	github.com/fxamacker/cbor/v2 v2.7.0
-	golang.org/x/tools v0.17.0
+	golang.org/x/tools v0.30.0
 )
 
-require github.com/x448/float16 v0.8.4 // indirect
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
@@ -27,11 +27,13 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/fxamacker/cbor/v2"
@@ -49,6 +51,7 @@ const (
 	separator        = "_"
 	EnterSuffix      = "Enter"
 	LeaveSuffix      = "Leave"
+	PanicSuffix      = "Panic"
 	CallDepth        = 5
 )
 
@@ -171,6 +174,53 @@ func (l *Logger) LogLeave(id uint64, name string, args []any, results []any) {
 	l.Log(funcCall)
 }
 
+// LogLeaveTimed logs a function exit together with the time elapsed since start, which
+// is taken with Now when the function is entered.
+func (l *Logger) LogLeaveTimed(id uint64, name string, args []any, results []any, start time.Time) {
+	duration := time.Since(start)
+
+	formattedArgs := make([]string, 0, len(args)+1)
+	formattedArgs = append(formattedArgs, strconv.FormatUint(id, 10))
+	for _, arg := range args {
+		formattedArgs = append(formattedArgs, format(arg))
+	}
+
+	formattedResults := make([]string, 0, len(results))
+	for _, result := range results {
+		formattedResults = append(formattedResults, format(result))
+	}
+
+	l.Log(&FuncCall{
+		Name:     name + separator + LeaveSuffix,
+		Args:     formattedArgs,
+		Results:  formattedResults,
+		Time:     start.Add(duration),
+		Duration: duration,
+	})
+}
+
+// LogPanic logs a panic unwinding through a function. The event carries the formatted
+// panic value and, if stack is set, the stack of the panicking goroutine.
+func (l *Logger) LogPanic(id uint64, name string, args []any, value any, stack bool) {
+	formattedArgs := make([]string, 0, len(args)+1)
+	formattedArgs = append(formattedArgs, strconv.FormatUint(id, 10))
+	for _, arg := range args {
+		formattedArgs = append(formattedArgs, format(arg))
+	}
+
+	results := []string{format(value)}
+	if stack {
+		results = append(results, strconv.Quote(string(debug.Stack())))
+	}
+
+	l.Log(&FuncCall{
+		Name:    name + separator + PanicSuffix,
+		Args:    formattedArgs,
+		Results: results,
+		Time:    time.Now(),
+	})
+}
+
 // NewLogger creates a new Logger instance with the specified format.
 // This allows for instance-based logging instead of relying on global state.
 func NewLogger(format Format) *Logger {
@@ -438,22 +488,30 @@ func formatEvent(fn *FuncCall, format Format) []byte {
 // remain the same as your original file.
 
 func (f *FuncCall) String() string {
+	var s string
 	if len(f.Results) == 0 {
-		return fmt.Sprintf("%s(%s)", f.Name, strings.Join(f.Args, ", "))
+		s = fmt.Sprintf("%s(%s)", f.Name, strings.Join(f.Args, ", "))
+	} else {
+		s = fmt.Sprintf("%s(%s) = (%s)", f.Name, strings.Join(f.Args, ", "), strings.Join(f.Results, ", "))
 	}
-	return fmt.Sprintf("%s(%s) = (%s)", f.Name, strings.Join(f.Args, ", "), strings.Join(f.Results, ", "))
+	if f.Duration > 0 {
+		s += " [" + f.Duration.String() + "]"
+	}
+	return s
 }
 
 type FuncCall struct {
-	Name    string    `json:"name" cbor:"name"`
-	Args    []string  `json:"args" cbor:"args"`
-	Results []string  `json:"results" cbor:"results"`
-	Time    time.Time `json:"time" cbor:"time"`
+	Name     string        `json:"name" cbor:"name"`
+	Args     []string      `json:"args" cbor:"args"`
+	Results  []string      `json:"results" cbor:"results"`
+	Time     time.Time     `json:"time" cbor:"time"`
+	Duration time.Duration `json:"duration,omitempty" cbor:"duration,omitempty"` // Only set for timed Leave events
 }
 
 type TimedEvent struct {
-	Time  int64     `json:"time" cbor:"time"`
-	Event *WeakTerm `json:"event" cbor:"event"`
+	Time     int64     `json:"time" cbor:"time"`
+	Duration int64     `json:"duration,omitempty" cbor:"duration,omitempty"` // Nanoseconds since the matching Enter
+	Event    *WeakTerm `json:"event" cbor:"event"`
 }
 
 type WeakTerm struct {
@@ -504,7 +562,8 @@ func (f *FuncCall) toTimedEvent() *TimedEvent {
 
 	// Create the timed event structure
 	timedEvent := &TimedEvent{
-		Time: f.Time.UnixNano(),
+		Time:     f.Time.UnixNano(),
+		Duration: f.Duration.Nanoseconds(),
 		Event: &WeakTerm{
 			Name: PairFunctionName,
 			Type: "function",
@@ -539,9 +598,73 @@ var formatBufferPool = sync.Pool{
 	},
 }
 
-// format converts any value to its string representation optimized for performance.
-// This is a hot path function called for every logged argument and result.
+// redacted is the type of Redacted.
+type redacted struct{}
+
+// Redacted is logged by instrumented functions in place of arguments and results
+// marked with the //annotate:redact directive.
+var Redacted = redacted{}
+
+// formatLength is the maximum length of a formatted value, 0 means unlimited.
+var formatLength atomic.Int64
+
+// SetFormatLength limits the formatted length of each logged argument and result to n.
+// Longer values are truncated and marked with their original length, see truncate.
+// Instrumented packages call it on initialization; n <= 0 removes the limit.
+func SetFormatLength(n int) {
+	formatLength.Store(int64(n))
+}
+
+// format converts any value to its string representation, truncated to the configured
+// format length. Strings and byte slices are cut before formatting, so that huge
+// buffers are never formatted in full.
 func format(i any) string {
+	limit := int(formatLength.Load())
+	if limit <= 0 {
+		return formatValue(i)
+	}
+
+	switch v := i.(type) {
+	case []byte:
+		// Two hex digits per byte after the 0x prefix.
+		if n := max(limit-2, 0) / 2; len(v) > n {
+			return truncate(formatValue(v[:n]), len(v))
+		}
+	case string:
+		// Leave room for the quotes.
+		if n := max(limit-2, 0); len(v) > n {
+			return truncate(formatValue(cutUTF8(v, n)), len(v))
+		}
+	}
+
+	s := formatValue(i)
+	if len(s) > limit {
+		return truncate(cutUTF8(s, limit), len(s))
+	}
+	return s
+}
+
+// truncate appends the truncation marker to a formatted prefix, recording the original
+// length in bytes of the string or byte slice, or of the formatted value otherwise.
+func truncate(prefix string, length int) string {
+	return prefix + "...(len " + strconv.Itoa(length) + ")"
+}
+
+// cutUTF8 returns the longest prefix of s that is at most n bytes long and does not
+// split a UTF-8 sequence.
+func cutUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// formatValue converts any value to its string representation optimized for performance.
+// This is a hot path function called for every logged argument and result.
+func formatValue(i any) string {
 	if i == nil {
 		return ""
 	}
@@ -642,6 +765,9 @@ func format(i any) string {
 	case string:
 		return fmt.Sprintf("%q", v)
 
+	case redacted:
+		return "<redacted>"
+
 	// Boolean fast path - avoid string allocation
 	case bool:
 		if v {
@@ -709,6 +835,12 @@ func (l *Logger) ID() uint64 {
 	return atomic.AddUint64(&l.counter, 1)
 }
 
+// TypeName returns the name of the type argument T. Instrumented generic functions use
+// it to name their events after the instantiation, e.g. Stack[int]_Push.
+func TypeName[T any]() string {
+	return reflect.TypeFor[T]().String()
+}
+
 // IsCalledFrom returns true if the function call stack contains the search string.
 func IsCalledFrom(s string) bool {
 	pcs := make([]uintptr, CallDepth)
@@ -765,6 +897,26 @@ func LogLeave(id uint64, name string, args []any, results []any) {
 	}
 }
 
+// LogLeaveTimed logs a function exit with its duration using the global logger.
+func LogLeaveTimed(id uint64, name string, args []any, results []any, start time.Time) {
+	if defaultLogger != nil {
+		defaultLogger.LogLeaveTimed(id, name, args, results, start)
+	}
+}
+
+// Now returns the current time including the monotonic clock reading. Instrumented
+// functions take it on entry when timing is enabled.
+func Now() time.Time {
+	return time.Now()
+}
+
+// LogPanic logs a panic unwinding through a function using the global logger.
+func LogPanic(id uint64, name string, args []any, value any, stack bool) {
+	if defaultLogger != nil {
+		defaultLogger.LogPanic(id, name, args, value, stack)
+	}
+}
+
 // Log logs a function call using the global logger.
 func Log(fn *FuncCall) {
 	if defaultLogger != nil {
@@ -19,10 +19,14 @@ package log
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -72,6 +76,35 @@ func TestFormat(t *testing.T) {
 		{"bool false", false, "0"},
 		{"byte slice", []byte{0x48, 0x65, 0x6c, 0x6c, 0x6f}, "0x48656c6c6f"},
 		{"empty byte slice", []byte{}, "0x"},
+		{"redacted", Redacted, "<redacted>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := format(tc.input)
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatLength(t *testing.T) {
+	SetFormatLength(10)
+	defer SetFormatLength(0)
+
+	testCases := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{"short string", "hello", `"hello"`},
+		{"long string", "hello, world", `"hello, w"...(len 12)`},
+		{"multi-byte string", "ääääää", `"ääää"...(len 12)`},
+		{"short byte slice", []byte{1, 2, 3, 4}, "0x01020304"},
+		{"long byte slice", make([]byte, 1<<20), "0x00000000...(len 1048576)"},
+		{"long error", fmt.Errorf("connection refused"), `"connectio...(len 20)`},
+		{"int", 1234567890123, "1234567890...(len 13)"},
 	}
 
 	for _, tc := range testCases {
@@ -173,6 +206,61 @@ func TestFormatEvent(t *testing.T) {
 	}
 }
 
+func TestFormatEventDuration(t *testing.T) {
+	fc := &FuncCall{
+		Name:     "test_func_Leave",
+		Args:     []string{"1"},
+		Results:  []string{"3"},
+		Time:     time.Now(),
+		Duration: 1500 * time.Microsecond,
+	}
+
+	if text := string(formatEvent(fc, FormatText)); text != "test_func_Leave(1) = (3) [1.5ms]\n" {
+		t.Errorf("Unexpected text event %q", text)
+	}
+
+	var jsonEvent TimedEvent
+	if err := json.Unmarshal(formatEvent(fc, FormatJSON), &jsonEvent); err != nil {
+		t.Fatalf("Invalid JSON produced: %v", err)
+	}
+	if jsonEvent.Duration != 1500000 {
+		t.Errorf("Expected JSON duration 1500000, got %d", jsonEvent.Duration)
+	}
+
+	var cborEvent TimedEvent
+	if err := cbor.Unmarshal(formatEvent(fc, FormatCBOR), &cborEvent); err != nil {
+		t.Fatalf("Invalid CBOR produced: %v", err)
+	}
+	if cborEvent.Duration != 1500000 {
+		t.Errorf("Expected CBOR duration 1500000, got %d", cborEvent.Duration)
+	}
+
+	// Untimed events do not carry a duration.
+	fc.Duration = 0
+	if data := string(formatEvent(fc, FormatJSON)); strings.Contains(data, "duration") {
+		t.Errorf("Untimed JSON event contains a duration: %s", data)
+	}
+}
+
+func TestLogLeaveTimed(t *testing.T) {
+	logger := NewLogger(FormatText)
+
+	start := Now()
+	time.Sleep(time.Millisecond)
+	logger.LogLeaveTimed(1, "testFunc", []any{42}, []any{"result"}, start)
+
+	event := <-logger.eventBuffer
+	if event.Name != "testFunc_Leave" {
+		t.Errorf("Expected testFunc_Leave, got %q", event.Name)
+	}
+	if event.Duration < time.Millisecond {
+		t.Errorf("Expected a duration of at least 1ms, got %v", event.Duration)
+	}
+	if !reflect.DeepEqual(event.Results, []string{`"result"`}) {
+		t.Errorf("Unexpected results %v", event.Results)
+	}
+}
+
 func TestLogEnterLeave(t *testing.T) {
 	logger := NewLogger(FormatText)
 
@@ -224,6 +312,39 @@ func TestLogEnterLeave(t *testing.T) {
 	}
 }
 
+func TestLogPanic(t *testing.T) {
+	logger := NewLogger(FormatText)
+
+	logger.LogPanic(1, "testFunc", []any{42}, fmt.Errorf("boom"), false)
+	logger.LogPanic(2, "testFunc", []any{42}, "boom", true)
+
+	event := <-logger.eventBuffer
+	if event.Name != "testFunc_Panic" {
+		t.Errorf("Expected testFunc_Panic, got %q", event.Name)
+	}
+	if !reflect.DeepEqual(event.Args, []string{"1", "42"}) {
+		t.Errorf("Unexpected arguments %v", event.Args)
+	}
+	if !reflect.DeepEqual(event.Results, []string{`"boom"`}) {
+		t.Errorf("Unexpected results %v", event.Results)
+	}
+
+	event = <-logger.eventBuffer
+	if len(event.Results) != 2 || !strings.Contains(event.Results[1], "TestLogPanic") {
+		t.Errorf("Panic event does not carry the stack: %v", event.Results)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	if name := TypeName[int](); name != "int" {
+		t.Errorf("Expected int, got %q", name)
+	}
+
+	if name := TypeName[map[string][]byte](); name != "map[string][]uint8" {
+		t.Errorf("Expected map[string][]uint8, got %q", name)
+	}
+}
+
 func TestIsSocketAddress(t *testing.T) {
 	testCases := []struct {
 		addr     string
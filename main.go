@@ -0,0 +1,305 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+// stringList is a flag that can be repeated to collect several values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// isPackagePattern reports whether a command line argument is a package pattern
+// such as ./... rather than the path of a single Go source file.
+func isPackagePattern(arg string) bool {
+	return !strings.HasSuffix(arg, ".go")
+}
+
+// writeTheory writes the monitoring rules of the instrumented functions to a file.
+func writeTheory(outputPath string, functions []annotate.Function) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open theory file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(annotate.GenerateTheory(functions))
+	if err != nil {
+		return fmt.Errorf("failed to write theory: %w", err)
+	}
+
+	return nil
+}
+
+// manifestFile is the JSON document written by the -manifest flag.
+type manifestFile struct {
+	Functions []annotate.Function `json:"functions"`
+}
+
+// writeManifest writes the instrumented functions as JSON to a file.
+func writeManifest(outputPath string, functions []annotate.Function) error {
+	if functions == nil {
+		functions = []annotate.Function{}
+	}
+
+	data, err := json.MarshalIndent(manifestFile{Functions: functions}, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// main processes command line arguments and orchestrates the code annotation workflow.
+func main() {
+	command, args, goArgs := splitCommand(os.Args[1:])
+
+	var config Config
+	flag.BoolVar(&config.ShowReturn, "returns", false, "show function return")
+	flag.BoolVar(&config.ExportedOnly, "exported", false, "only annotate exported functions")
+	flag.StringVar(&config.Prefix, "prefix", "", "log prefix")
+	flag.BoolVar(&config.ShowPackage, "package", false, "show package name prefix on function calls")
+	flag.BoolVar(&config.WriteFiles, "w", false, "re-write files in place")
+	flag.BoolVar(&config.List, "l", false, "list files whose source would change")
+	flag.BoolVar(&config.Diff, "d", false, "print a unified diff of the changes to each file")
+	flag.StringVar(&config.OutputDir, "o", "", "write processed files to this directory, keeping their layout relative to the working directory")
+	flag.IntVar(&config.FormatLength, "formatLength", 1024, "limit the formatted length of each argument to 'size'")
+	flag.BoolVar(&config.Timing, "timing", false, "print function durations. Implies -returns")
+	flag.StringVar(&config.ImportPath, "import", "", "import path for the log package")
+	flag.StringVar(&config.GeneratePath, "generate", "", "rule path for monitoring rules")
+	flag.StringVar(&config.ManifestPath, "manifest", "", "write a JSON manifest of the instrumented functions to this file")
+	flag.StringVar(&config.BuildTags, "tags", "", "comma-separated list of build tags used when loading packages")
+	flag.BoolVar(&config.Closures, "closures", false, "also annotate function literals")
+	flag.BoolVar(&config.Defer, "defer", false, "keep function bodies in place and log results from the deferred epilogue, naming unnamed results")
+	flag.BoolVar(&config.Stack, "stack", false, "include the stack in panic events")
+	flag.BoolVar(&config.Types, "types", false, "type-check packages to record static types and log named types without reflection")
+	flag.Var((*stringList)(&config.Include), "include", "only annotate functions whose qualified name matches this regular expression (repeatable)")
+	flag.Var((*stringList)(&config.Exclude), "exclude", "do not annotate functions whose qualified name matches this regular expression (repeatable)")
+	flag.Var((*stringList)(&config.IncludeFiles), "include-files", "only annotate files whose base name matches this glob (repeatable)")
+	flag.Var((*stringList)(&config.ExcludeFiles), "exclude-files", "do not annotate files whose base name matches this glob (repeatable)")
+	flag.BoolVar(&config.LineDirectives, "line", false, "emit //line directives so that positions refer to the original source. Implied by -overlay")
+	flag.IntVar(&config.Jobs, "j", 0, "number of files to process concurrently (default: number of CPUs)")
+	flag.StringVar(&config.CacheDir, "cache", "", "reuse processed files from this directory if neither they nor the options changed (default for go commands: user cache directory)")
+	flag.StringVar(&config.TemplateFile, "template-file", "", "file defining the templates \"enter\" and \"leave\" that generate the instrumentation")
+	flag.BoolVar(&config.KeepGoing, "keep-going", false, "skip functions that cannot be instrumented and report them at the end")
+	flag.BoolVar(&config.Verify, "verify", false, "type-check the annotated packages and only write files if they compile")
+	flag.BoolVar(&config.Strip, "strip", false, "remove instrumentation added by go-annotate")
+	flag.StringVar(&config.OverlayDir, "overlay", "", "write annotated copies and "+overlayFileName+" to this directory instead of rewriting files")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <source-files | packages>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s build|test|run [options] [packages] -- <go args>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		log.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	targets := flag.Args()
+	if command != "" {
+		if len(targets) == 0 {
+			targets = []string{"./..."}
+		}
+
+		if config.OverlayDir == "" {
+			dir, err := defaultDir("overlay")
+			if err != nil {
+				log.Fatalf("Failed to determine overlay directory: %v", err)
+			}
+			config.OverlayDir = dir
+		}
+		if config.CacheDir == "" {
+			dir, err := defaultDir("cache")
+			if err != nil {
+				log.Fatalf("Failed to determine cache directory: %v", err)
+			}
+			config.CacheDir = dir
+		}
+	}
+
+	if config.OutputDir != "" && config.OverlayDir != "" {
+		log.Fatalf("-o cannot be combined with -overlay or a go command")
+	}
+
+	// Annotated copies in the overlay are compiled in place of the original files.
+	if config.OverlayDir != "" {
+		config.LineDirectives = true
+	}
+
+	if config.TemplateFile != "" {
+		data, err := os.ReadFile(config.TemplateFile)
+		if err != nil {
+			log.Fatalf("Failed to read template file: %v", err)
+		}
+		config.Template = string(data)
+	}
+
+	if len(targets) < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Settings from configuration files apply unless given on the command line.
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	project := newProjectConfig(config, explicit)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to determine working directory: %v", err)
+	}
+	if err := project.fixFormatLength(cwd); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	projectConfig, err := project.configForDir(cwd)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	config = *projectConfig
+
+	// Files are annotated with the configuration resolved for their directory.
+	config.FileOptions = project.optionsFor
+
+	annotator, err := annotate.New(&config.Options)
+	if err != nil {
+		log.Fatalf("Failed to create annotator: %v", err)
+	}
+
+	var files, patterns []string
+	for _, arg := range targets {
+		if isPackagePattern(arg) {
+			patterns = append(patterns, arg)
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	if config.Types && len(files) > 0 {
+		queries := make([]string, len(files))
+		for i, file := range files {
+			queries[i] = "file=" + file
+		}
+
+		pkgs, err := annotator.LoadPackages("", queries...)
+		if err != nil {
+			log.Fatalf("Failed to type-check files: %v", err)
+		}
+		for _, pkg := range pkgs {
+			annotator.AddTypes(pkg)
+		}
+	}
+
+	failed := false
+	results, err := annotator.AnnotateFiles(files...)
+	if err != nil {
+		log.Printf("Error processing files:\n%v", err)
+		failed = true
+	}
+
+	if len(patterns) > 0 {
+		pkgs, err := annotator.LoadPackages("", patterns...)
+		if err != nil {
+			log.Fatalf("Failed to load packages: %v", err)
+		}
+
+		pkgResults, err := annotator.AnnotatePackages(pkgs...)
+		results = append(results, pkgResults...)
+		if err != nil {
+			log.Printf("Error processing packages:\n%v", err)
+			failed = true
+		}
+	}
+
+	if !config.Strip {
+		summary := annotator.Summary()
+		for _, err := range summary.Errors {
+			log.Printf("Skipped %v", err)
+		}
+		log.Print(summary)
+	}
+
+	if config.Verify {
+		if err := annotator.Verify(results); err != nil {
+			log.Fatalf("Verification failed, no files were written:\n%v", err)
+		}
+	}
+
+	w := newWriter(&config)
+	for _, result := range results {
+		if err := w.write(result); err != nil {
+			log.Printf("Error writing file %s: %v", result.Filename, err)
+			failed = true
+		}
+	}
+
+	if config.GeneratePath != "" {
+		if err := writeTheory(config.GeneratePath, annotator.Manifest()); err != nil {
+			log.Fatalf("Failed to write theory: %v", err)
+		}
+	}
+
+	if config.ManifestPath != "" {
+		if err := writeManifest(config.ManifestPath, annotator.Manifest()); err != nil {
+			log.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+
+	if command != "" {
+		// Building the unannotated sources would hide the failure.
+		if failed {
+			log.Fatalf("Not running go %s, as some files could not be annotated or written", command)
+		}
+		code, err := w.runGoCommand(command, goArgs)
+		if err != nil {
+			log.Fatalf("Failed to run go %s: %v", command, err)
+		}
+		os.Exit(code)
+	}
+
+	if config.OverlayDir != "" {
+		if err := w.writeOverlay(filepath.Join(config.OverlayDir, overlayFileName)); err != nil {
+			log.Fatalf("Failed to write overlay: %v", err)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
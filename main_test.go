@@ -0,0 +1,129 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+// writeModule creates a temporary module from a map of relative paths to file contents.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestIsPackagePattern(t *testing.T) {
+	testCases := []struct {
+		arg      string
+		expected bool
+	}{
+		{"./...", true},
+		{"example.com/svc/internal/...", true},
+		{".", true},
+		{"main.go", false},
+		{"cmd/svc/main.go", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.arg, func(t *testing.T) {
+			if result := isPackagePattern(tc.arg); result != tc.expected {
+				t.Errorf("Expected %v for %q, got %v", tc.expected, tc.arg, result)
+			}
+		})
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	annotator, err := annotate.New(&annotate.Options{ImportPath: "github.com/test/log"})
+	if err != nil {
+		t.Fatalf("annotate.New failed: %v", err)
+	}
+
+	code := "package svc\n\nfunc Div(a, b int) (q int, err error) {\n\treturn a / b, nil\n}\n"
+	if _, err := annotator.AnnotateSource("svc.go", []byte(code)); err != nil {
+		t.Fatalf("AnnotateSource failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := writeManifest(path, annotator.Manifest()); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	for _, key := range []string{`"name": "svc_Div"`, `"file": "svc.go"`, `"line": 3`, `"namedResults": true`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Manifest does not contain %s:\n%s", key, data)
+		}
+	}
+
+	var manifest manifestFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Invalid manifest JSON: %v", err)
+	}
+	if len(manifest.Functions) != 1 || manifest.Functions[0].Params[1].Name != "b" || manifest.Functions[0].Results[0].Name != "q" {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+
+	if err := writeManifest(path, nil); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"functions": []`) {
+		t.Errorf("Empty manifest is not a list: %s", data)
+	}
+}
+
+func TestWriteTheoryTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.thy")
+	functions := []annotate.Function{{Name: "svc_Div"}, {Name: "svc_Mul"}}
+	if err := writeTheory(path, functions); err != nil {
+		t.Fatalf("writeTheory failed: %v", err)
+	}
+
+	// Fewer rules, for example after adding filters, must not leave stale ones behind.
+	if err := writeTheory(path, functions[:1]); err != nil {
+		t.Fatalf("writeTheory failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read theory: %v", err)
+	}
+	if string(data) != annotate.GenerateTheory(functions[:1]) {
+		t.Errorf("Theory was not replaced:\n%s", data)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+// writer writes processed files as selected on the command line and records the
+// annotated copies written to the overlay directory.
+type writer struct {
+	config  *Config
+	overlay map[string]string
+}
+
+// newWriter creates a writer for the given configuration.
+func newWriter(config *Config) *writer {
+	return &writer{
+		config:  config,
+		overlay: make(map[string]string),
+	}
+}
+
+// write writes the processed source of a file to the overlay, the output directory, to
+// stdout or in place. Listing and diffing show the changes to the file first.
+func (w *writer) write(r *annotate.Result) error {
+	if r.Changed() {
+		w.showChanges(r)
+	}
+
+	switch {
+	case w.config.OverlayDir != "":
+		return w.writeOverlayCopy(r.Filename, r.Output)
+	case w.config.OutputDir != "":
+		return w.writeOutputCopy(r.Filename, r.Output)
+	case !w.config.WriteFiles:
+		if w.config.List || w.config.Diff {
+			return nil
+		}
+		fmt.Println(string(r.Output))
+		return nil
+	}
+
+	if err := os.WriteFile(r.Filename, r.Output, 0); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", r.Filename, err)
+	}
+
+	return nil
+}
+
+// showChanges lists a changed file or prints its diff.
+func (w *writer) showChanges(r *annotate.Result) {
+	if w.config.List {
+		fmt.Println(r.Filename)
+	}
+	if w.config.Diff {
+		os.Stdout.Write(unifiedDiff(r.Filename+".orig", r.Source, r.Filename, r.Output))
+	}
+}
+
+// outputPath returns the location of file inside the output directory. The path of the
+// file relative to the working directory is kept, so files outside of it are rejected.
+func (w *writer) outputPath(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", file, err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine working directory: %w", err)
+	}
+
+	rel, err := filepath.Rel(cwd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %s is outside of the working directory", file)
+	}
+
+	return filepath.Join(w.config.OutputDir, rel), nil
+}
+
+// writeOutputCopy writes the processed source of file into the output directory.
+func (w *writer) writeOutputCopy(file string, src []byte) error {
+	path, err := w.outputPath(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory for %s: %w", file, err)
+	}
+
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		return fmt.Errorf("failed to write output copy of %s: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/specmon/go-annotate/annotate"
+)
+
+// chdir changes the working directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+}
+
+// annotatedResult returns the result of annotating a source file with a single
+// instrumented statement.
+func annotatedResult(t *testing.T, file string) *annotate.Result {
+	t.Helper()
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read source: %v", err)
+	}
+
+	output := strings.Replace(string(src), "func main() {}", "func main() {\n\t__log.LogEnter()\n}", 1)
+	return &annotate.Result{Filename: file, Source: src, Output: []byte(output)}
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"cmd/app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	outputDir := t.TempDir()
+	chdir(t, dir)
+
+	w := newWriter(&Config{OutputDir: outputDir})
+
+	source := filepath.Join("cmd", "app", "main.go")
+	if err := w.write(annotatedResult(t, source)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	orig, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Failed to read source: %v", err)
+	}
+	if strings.Contains(string(orig), "LogEnter") {
+		t.Error("Source file was modified with an output directory")
+	}
+
+	annotated, err := os.ReadFile(filepath.Join(outputDir, source))
+	if err != nil {
+		t.Fatalf("Failed to read output copy: %v", err)
+	}
+	if !strings.Contains(string(annotated), "LogEnter") {
+		t.Error("Output copy is not instrumented")
+	}
+
+	outside := writeModule(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	err = w.write(annotatedResult(t, filepath.Join(outside, "main.go")))
+	if err == nil || !strings.Contains(err.Error(), "outside of the working directory") {
+		t.Errorf("Expected error for file outside the working directory, got %v", err)
+	}
+}
+
+func TestWriteListDiff(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"constant.go": "package main\n\nconst answer = 42\n",
+	})
+
+	w := newWriter(&Config{List: true, Diff: true})
+
+	old := os.Stdout
+	r, pw, _ := os.Pipe()
+	os.Stdout = pw
+
+	for _, name := range []string{"constant.go", "main.go"} {
+		if err := w.write(annotatedResult(t, filepath.Join(dir, name))); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	pw.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	output := buf.String()
+
+	source := filepath.Join(dir, "main.go")
+	if !strings.HasPrefix(output, source+"\ndiff "+source+".orig "+source+"\n") {
+		t.Errorf("Expected listing and diff of main.go, got:\n%s", output)
+	}
+	if strings.Contains(output, "constant.go") {
+		t.Errorf("Unchanged file was listed:\n%s", output)
+	}
+	if !strings.Contains(output, "\n+\t__log.LogEnter(") {
+		t.Errorf("Diff does not contain the instrumentation:\n%s", output)
+	}
+
+	orig, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Failed to read source: %v", err)
+	}
+	if strings.Contains(string(orig), "LogEnter") {
+		t.Error("Source file was modified when listing")
+	}
+}
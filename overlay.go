@@ -0,0 +1,153 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// overlayFileName is the name of the overlay file written by the -overlay flag.
+const overlayFileName = "overlay.json"
+
+// goCommands lists the go subcommands that can be wrapped with an instrumentation overlay.
+var goCommands = map[string]bool{
+	"build": true,
+	"test":  true,
+	"run":   true,
+}
+
+// overlayFile is the JSON document understood by the -overlay flag of the go command.
+type overlayFile struct {
+	Replace map[string]string
+}
+
+// splitCommand separates a wrapped go subcommand and the arguments after "--"
+// from the arguments meant for go-annotate itself.
+func splitCommand(args []string) (string, []string, []string) {
+	var command string
+	if len(args) > 0 && goCommands[args[0]] {
+		command = args[0]
+		args = args[1:]
+	}
+
+	if command == "" {
+		return "", args, nil
+	}
+
+	for i, arg := range args {
+		if arg == "--" {
+			return command, args[:i], args[i+1:]
+		}
+	}
+
+	return command, args, nil
+}
+
+// defaultDir returns a directory below the user cache directory, used for annotated
+// copies and the cache when wrapping the go command.
+func defaultDir(name string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "go-annotate", name), nil
+}
+
+// overlayPath returns the location of the annotated copy of file inside the overlay directory.
+// The absolute source path is mirrored below the overlay directory so files never collide.
+func (w *writer) overlayPath(file string) (string, string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve path %s: %w", file, err)
+	}
+
+	rel := abs[len(filepath.VolumeName(abs)):]
+	return abs, filepath.Join(w.config.OverlayDir, rel), nil
+}
+
+// writeOverlayCopy writes the annotated source of file into the overlay directory
+// and records the replacement for the overlay file.
+func (w *writer) writeOverlayCopy(file string, src []byte) error {
+	abs, path, err := w.overlayPath(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create overlay directory for %s: %w", file, err)
+	}
+
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		return fmt.Errorf("failed to write overlay copy of %s: %w", file, err)
+	}
+
+	w.overlay[abs] = path
+	return nil
+}
+
+// writeOverlay writes the overlay file mapping every annotated source file to its annotated copy.
+func (w *writer) writeOverlay(outputPath string) error {
+	data, err := json.MarshalIndent(overlayFile{Replace: w.overlay}, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to encode overlay: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write overlay: %w", err)
+	}
+
+	return nil
+}
+
+// runGoCommand runs "go <command> -overlay=<overlay> <args>" with the annotated copies
+// and returns the exit code of the go command.
+func (w *writer) runGoCommand(command string, args []string) (int, error) {
+	overlay, err := os.CreateTemp("", "go-annotate-*.json")
+	if err != nil {
+		return 1, fmt.Errorf("failed to create overlay file: %w", err)
+	}
+	overlay.Close()
+	defer os.Remove(overlay.Name())
+
+	if err := w.writeOverlay(overlay.Name()); err != nil {
+		return 1, err
+	}
+
+	cmd := exec.Command("go", append([]string{command, "-overlay=" + overlay.Name()}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("failed to run go %s: %w", command, err)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2025 CISPA Helmholtz Center for Information Security
+// Author: Kevin Morio <kevin.morio@cispa.de>
+//
+// This file is part of go-annotate.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the MIT License as published by
+// the Open Source Initiative.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// MIT License for more details.
+//
+// You should have received a copy of the MIT License
+// along with this program. If not, see <https://opensource.org/licenses/MIT>.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		command string
+		own     []string
+		goArgs  []string
+	}{
+		{
+			name: "plain invocation",
+			args: []string{"-w", "main.go"},
+			own:  []string{"-w", "main.go"},
+		},
+		{
+			name:    "wrapped build",
+			args:    []string{"build", "-import", "x/log", "./...", "--", "-o", "svc", "./cmd/svc"},
+			command: "build",
+			own:     []string{"-import", "x/log", "./..."},
+			goArgs:  []string{"-o", "svc", "./cmd/svc"},
+		},
+		{
+			name:    "wrapped test without go args",
+			args:    []string{"test", "-import", "x/log"},
+			command: "test",
+			own:     []string{"-import", "x/log"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command, own, goArgs := splitCommand(tc.args)
+			if command != tc.command {
+				t.Errorf("Expected command %q, got %q", tc.command, command)
+			}
+			if !reflect.DeepEqual(own, tc.own) {
+				t.Errorf("Expected own args %v, got %v", tc.own, own)
+			}
+			if !reflect.DeepEqual(goArgs, tc.goArgs) {
+				t.Errorf("Expected go args %v, got %v", tc.goArgs, goArgs)
+			}
+		})
+	}
+}
+
+func TestWriteOverlay(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	overlayDir := t.TempDir()
+	source := filepath.Join(dir, "main.go")
+
+	w := newWriter(&Config{WriteFiles: true, OverlayDir: overlayDir})
+	if err := w.write(annotatedResult(t, source)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	orig, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("Failed to read source: %v", err)
+	}
+	if strings.Contains(string(orig), "LogEnter") {
+		t.Error("Source file was modified in overlay mode")
+	}
+
+	overlayPath := filepath.Join(overlayDir, overlayFileName)
+	if err := w.writeOverlay(overlayPath); err != nil {
+		t.Fatalf("writeOverlay failed: %v", err)
+	}
+
+	data, err := os.ReadFile(overlayPath)
+	if err != nil {
+		t.Fatalf("Failed to read overlay: %v", err)
+	}
+
+	var overlay overlayFile
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		t.Fatalf("Invalid overlay JSON: %v", err)
+	}
+
+	replacement, ok := overlay.Replace[source]
+	if !ok {
+		t.Fatalf("Overlay has no replacement for %s: %v", source, overlay.Replace)
+	}
+	if !strings.HasPrefix(replacement, overlayDir) {
+		t.Errorf("Replacement %s is outside the overlay directory", replacement)
+	}
+
+	annotated, err := os.ReadFile(replacement)
+	if err != nil {
+		t.Fatalf("Failed to read annotated copy: %v", err)
+	}
+	if !strings.Contains(string(annotated), "LogEnter") {
+		t.Error("Annotated copy is not instrumented")
+	}
+}